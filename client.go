@@ -0,0 +1,40 @@
+package ritago
+
+import (
+	"context"
+	"time"
+)
+
+// Client is the set of operations of RitaClient, so code using the client can be
+// tested against a fake such as the one in the ritatest package. Fakes can build the
+// *Subscription of Subscribe with NewSubscription.
+type Client interface {
+	Ping(ctx context.Context) error
+	WaitForReady(ctx context.Context, interval time.Duration) error
+	GetCursor(channel string) (string, error)
+	GetCursorContext(ctx context.Context, channel string) (string, error)
+	GetCursors(channels []string) (map[string]string, error)
+	GetCursorsContext(ctx context.Context, channels []string) (map[string]string, error)
+	SendEvent(channel string, data interface{}) (string, error)
+	SendEventContext(ctx context.Context, channel string, data interface{}) (string, error)
+	SendEventAt(channel string, data interface{}, createdAt time.Time) (string, error)
+	SendEventWithKey(channel string, data interface{}, key string) (string, error)
+	ValidateEvent(channel string, data interface{}) error
+	SubEvent(channel string) (chan *RitaEvent, error)
+	SubEventSince(channel string, eventId string) (chan *RitaEvent, error)
+	SubEventSinceContext(ctx context.Context, channel string, eventId string) (chan *RitaEvent, error)
+	SubEventTail(channel string, n int) (chan *RitaEvent, error)
+	SubEventTailContext(ctx context.Context, channel string, n int) (chan *RitaEvent, error)
+	SubEventWS(channel string) (chan *RitaEvent, error)
+	Subscribe(ctx context.Context, channel string, eventId string, opts ...SubscribeOption) (*Subscription, error)
+	SubscribeWS(ctx context.Context, channel string, eventId string, opts ...SubscribeOption) (*Subscription, error)
+	GetEvents(channel string) ([]RitaEvent, error)
+	GetEventsSince(channel string, eventId string) ([]RitaEvent, error)
+	GetEventsSinceWith(channel string, eventId string, extra map[string]string) ([]RitaEvent, error)
+	GetEventsSinceContext(ctx context.Context, channel string, eventId string) ([]RitaEvent, error)
+	GetLatestEvents(channel string, n int) ([]RitaEvent, error)
+	TrimChannel(channel string, beforeId string) error
+	ClearChannel(channel string) error
+}
+
+var _ Client = (*RitaClient)(nil)
@@ -0,0 +1,51 @@
+//go:build go1.23
+
+package ritago
+
+import (
+	"context"
+	"iter"
+)
+
+/*
+Events returns an iterator over the events of the specified channel starting from the specified event ID.
+
+Events are fetched lazily, one page at a time, so memory stays flat for very large channels.
+The iteration stops after yielding the first error.
+
+Parameters:
+  - channel: The name of the channel from which to read events.
+  - since: The ID of the event after which to start reading. Empty reads from the start of the channel.
+
+Returns:
+  - iter.Seq2[*RitaEvent, error]: An iterator over the events of the channel.
+
+# Example
+
+	...
+	for event, err := range client.Events("test", "") {
+		if err != nil {
+			fmt.Println(err)
+			break
+		}
+		fmt.Println(event)
+	}
+	...
+*/
+func (c *RitaClient) Events(channel, since string) iter.Seq2[*RitaEvent, error] {
+	return func(yield func(*RitaEvent, error) bool) {
+		stopped := false
+
+		err := c.eachEvent(context.Background(), channel, since, func(event *RitaEvent) bool {
+			if !yield(event, nil) {
+				stopped = true
+				return false
+			}
+			return true
+		})
+
+		if err != nil && !stopped {
+			yield(nil, err)
+		}
+	}
+}
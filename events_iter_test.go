@@ -0,0 +1,55 @@
+//go:build go1.23
+
+package ritago
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEventsPagesLazily(t *testing.T) {
+	for _, inclusive := range []bool{false, true} {
+		requests := 0
+		server := newPagedServer(t, 250, 0, inclusive, &requests)
+		client := New(server.URL, "key")
+
+		count := 0
+		for event, err := range client.Events("test", "") {
+			if err != nil {
+				t.Fatal(err)
+			}
+			count++
+			if event.Id != fmt.Sprintf("%d-0", count) {
+				t.Fatalf("inclusive=%v: expected event %d, got %s", inclusive, count, event.Id)
+			}
+		}
+
+		if count != 250 {
+			t.Errorf("inclusive=%v: expected 250 events, got %d", inclusive, count)
+		}
+
+		requests = 0
+		for range client.Events("test", "") {
+			break
+		}
+
+		if requests != 1 {
+			t.Errorf("inclusive=%v: expected breaking early to fetch one page, got %d requests", inclusive, requests)
+		}
+	}
+}
+
+func TestEventsYieldsError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer server.Close()
+
+	for event, err := range New(server.URL, "key").Events("test", "") {
+		if event != nil || err != NotAuthorized {
+			t.Errorf("expected NotAuthorized, got %v, %v", event, err)
+		}
+	}
+}
@@ -0,0 +1,250 @@
+// Package websocket is a minimal client side implementation of the WebSocket
+// protocol (RFC 6455), enough to receive the messages of a subscription.
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/rand"
+	"crypto/sha1"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// readChunkSize is the largest payload allocated at once from the frame length.
+const readChunkSize = 64 << 10
+
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xA
+)
+
+var (
+	ErrBadHandshake    = errors.New("websocket: bad handshake")
+	ErrProtocol        = errors.New("websocket: protocol error")
+	ErrMessageTooLarge = errors.New("websocket: message too large")
+)
+
+// NewKey returns a random Sec-WebSocket-Key for a handshake.
+func NewKey() (string, error) {
+	key := make([]byte, 16)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+
+	return base64.StdEncoding.EncodeToString(key), nil
+}
+
+// AcceptKey returns the Sec-WebSocket-Accept the server answers for key.
+func AcceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// SetHandshakeHeaders adds the headers upgrading req to a WebSocket connection.
+func SetHandshakeHeaders(header http.Header, key string) {
+	header.Set("Connection", "Upgrade")
+	header.Set("Upgrade", "websocket")
+	header.Set("Sec-WebSocket-Version", "13")
+	header.Set("Sec-WebSocket-Key", key)
+}
+
+// Upgrade validates the handshake response to a request sent with key and
+// returns the connection. resp must have the status 101 Switching Protocols.
+func Upgrade(resp *http.Response, key string) (*Conn, error) {
+	if resp.StatusCode != http.StatusSwitchingProtocols ||
+		!strings.EqualFold(resp.Header.Get("Upgrade"), "websocket") ||
+		resp.Header.Get("Sec-WebSocket-Accept") != AcceptKey(key) {
+		return nil, ErrBadHandshake
+	}
+
+	rwc, ok := resp.Body.(io.ReadWriteCloser)
+	if !ok {
+		return nil, ErrBadHandshake
+	}
+
+	return &Conn{rwc: rwc, br: bufio.NewReader(rwc)}, nil
+}
+
+// Conn is a client WebSocket connection. ReadMessage must be called from a
+// single goroutine; Close may be called concurrently.
+type Conn struct {
+	// MaxMessageSize bounds the size of a message; zero means no bound.
+	MaxMessageSize int64
+
+	rwc       io.ReadWriteCloser
+	br        *bufio.Reader
+	wmu       sync.Mutex
+	closeOnce sync.Once
+}
+
+// ReadMessage returns the payload of the next text or binary message,
+// answering the pings received meanwhile. It returns io.EOF once the server
+// closes the connection.
+func (c *Conn) ReadMessage() ([]byte, error) {
+	var message []byte
+	started := false
+
+	for {
+		fin, op, payload, err := c.readFrame(int64(len(message)))
+		if err != nil {
+			return nil, err
+		}
+
+		switch op {
+		case opPing:
+			if err := c.writeFrame(opPong, payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			if len(payload) > 2 {
+				payload = payload[:2]
+			}
+			c.writeFrame(opClose, payload)
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			if (op == opContinuation) != started {
+				return nil, ErrProtocol
+			}
+			started = true
+			message = append(message, payload...)
+
+			if fin {
+				return message, nil
+			}
+		default:
+			return nil, ErrProtocol
+		}
+	}
+}
+
+// readFrame reads a single frame; buffered is the size of the message read so
+// far, counted against MaxMessageSize before allocating the payload.
+func (c *Conn) readFrame(buffered int64) (bool, byte, []byte, error) {
+	var head [2]byte
+	if _, err := io.ReadFull(c.br, head[:]); err != nil {
+		return false, 0, nil, err
+	}
+
+	fin := head[0]&0x80 != 0
+	op := head[0] & 0x0f
+	masked := head[1]&0x80 != 0
+	length := int64(head[1] & 0x7f)
+
+	switch length {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(c.br, ext[:]); err != nil {
+			return false, 0, nil, err
+		}
+		length = int64(binary.BigEndian.Uint64(ext[:]))
+		if length < 0 {
+			return false, 0, nil, ErrProtocol
+		}
+	}
+
+	if op >= opClose && (length > 125 || !fin) {
+		return false, 0, nil, ErrProtocol
+	}
+
+	if op < opClose && c.MaxMessageSize > 0 && buffered+length > c.MaxMessageSize {
+		return false, 0, nil, ErrMessageTooLarge
+	}
+
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(c.br, mask[:]); err != nil {
+			return false, 0, nil, err
+		}
+	}
+
+	// the length comes from the server: beyond a chunk the payload grows with
+	// the data actually read, so a bogus length can't allocate it upfront
+	var payload []byte
+	if length <= readChunkSize {
+		payload = make([]byte, length)
+		if _, err := io.ReadFull(c.br, payload); err != nil {
+			return false, 0, nil, err
+		}
+	} else {
+		var buf bytes.Buffer
+		n, err := io.CopyN(&buf, c.br, length)
+		if err == io.EOF && n > 0 {
+			err = io.ErrUnexpectedEOF
+		}
+		if err != nil {
+			return false, 0, nil, err
+		}
+		payload = buf.Bytes()
+	}
+
+	if masked {
+		for i := range payload {
+			payload[i] ^= mask[i%4]
+		}
+	}
+
+	return fin, op, payload, nil
+}
+
+// writeFrame writes a single masked frame, as required from clients.
+func (c *Conn) writeFrame(op byte, payload []byte) error {
+	frame := []byte{0x80 | op}
+
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, 0x80|byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 0x80|126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 0x80|127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	var mask [4]byte
+	if _, err := rand.Read(mask[:]); err != nil {
+		return err
+	}
+	frame = append(frame, mask[:]...)
+
+	for i, b := range payload {
+		frame = append(frame, b^mask[i%4])
+	}
+
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+
+	_, err := c.rwc.Write(frame)
+	return err
+}
+
+// Close sends a normal closure frame and closes the connection.
+func (c *Conn) Close() error {
+	err := error(nil)
+
+	c.closeOnce.Do(func() {
+		c.writeFrame(opClose, []byte{0x03, 0xe8})
+		err = c.rwc.Close()
+	})
+
+	return err
+}
@@ -0,0 +1,137 @@
+package websocket
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"testing"
+)
+
+// serverFrame encodes an unmasked frame, as sent by servers.
+func serverFrame(fin bool, op byte, payload []byte) []byte {
+	head := op
+	if fin {
+		head |= 0x80
+	}
+
+	frame := []byte{head}
+	switch {
+	case len(payload) < 126:
+		frame = append(frame, byte(len(payload)))
+	case len(payload) <= 0xffff:
+		frame = append(frame, 126)
+		frame = binary.BigEndian.AppendUint16(frame, uint16(len(payload)))
+	default:
+		frame = append(frame, 127)
+		frame = binary.BigEndian.AppendUint64(frame, uint64(len(payload)))
+	}
+
+	return append(frame, payload...)
+}
+
+// readClientFrame decodes a frame sent by the client, checking it is masked.
+func readClientFrame(t *testing.T, r io.Reader) (byte, []byte) {
+	t.Helper()
+
+	conn := &Conn{br: bufio.NewReader(r)}
+	var head [2]byte
+	if _, err := io.ReadFull(conn.br, head[:]); err != nil {
+		t.Fatal(err)
+	}
+	if head[1]&0x80 == 0 {
+		t.Fatalf("client frame not masked")
+	}
+	conn.br = bufio.NewReader(io.MultiReader(bytes.NewReader(head[:]), conn.br))
+
+	_, op, payload, err := conn.readFrame(0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return op, payload
+}
+
+func TestReadMessage(t *testing.T) {
+	client, server := net.Pipe()
+	defer client.Close()
+
+	conn := &Conn{rwc: client, br: bufio.NewReader(client)}
+
+	large := bytes.Repeat([]byte("x"), 70000)
+
+	go func() {
+		server.Write(serverFrame(true, opText, []byte(`{"id":"1-0"}`)))
+		server.Write(serverFrame(false, opText, []byte(`{"id":`)))
+		server.Write(serverFrame(true, opPing, []byte("hb")))
+		server.Write(serverFrame(true, opContinuation, []byte(`"2-0"}`)))
+		server.Write(serverFrame(true, opBinary, large))
+		server.Write(serverFrame(true, opClose, []byte{0x03, 0xe8}))
+	}()
+
+	message, err := conn.ReadMessage()
+	if err != nil || string(message) != `{"id":"1-0"}` {
+		t.Fatalf("unexpected message %q, %v", message, err)
+	}
+
+	done := make(chan []byte)
+	go func() {
+		message, err := conn.ReadMessage()
+		if err != nil {
+			t.Error(err)
+		}
+		done <- message
+	}()
+
+	if op, payload := readClientFrame(t, server); op != opPong || string(payload) != "hb" {
+		t.Errorf("expected pong hb, got %d %q", op, payload)
+	}
+
+	if message := <-done; string(message) != `{"id":"2-0"}` {
+		t.Errorf("expected fragmented message to be joined, got %q", message)
+	}
+
+	if message, err := conn.ReadMessage(); err != nil || !bytes.Equal(message, large) {
+		t.Errorf("expected large message, got %d bytes, %v", len(message), err)
+	}
+
+	go func() {
+		if op, _ := readClientFrame(t, server); op != opClose {
+			t.Errorf("expected the close to be answered, got %d", op)
+		}
+	}()
+
+	if _, err := conn.ReadMessage(); err != io.EOF {
+		t.Errorf("expected EOF on close, got %v", err)
+	}
+}
+
+func TestReadMessageTooLarge(t *testing.T) {
+	stream := serverFrame(true, opText, bytes.Repeat([]byte("x"), 200))
+
+	conn := &Conn{MaxMessageSize: 100, br: bufio.NewReader(bytes.NewReader(stream))}
+
+	if _, err := conn.ReadMessage(); err != ErrMessageTooLarge {
+		t.Errorf("expected ErrMessageTooLarge, got %v", err)
+	}
+}
+
+func TestReadMessageBogusLength(t *testing.T) {
+	frame := []byte{0x80 | opText, 127}
+	frame = binary.BigEndian.AppendUint64(frame, 1<<50)
+	stream := append(frame, "short"...)
+
+	conn := &Conn{br: bufio.NewReader(bytes.NewReader(stream))}
+
+	if _, err := conn.ReadMessage(); err != io.ErrUnexpectedEOF {
+		t.Errorf("expected io.ErrUnexpectedEOF, got %v", err)
+	}
+}
+
+func TestAcceptKey(t *testing.T) {
+	// example from RFC 6455, section 1.3
+	if accept := AcceptKey("dGhlIHNhbXBsZSBub25jZQ=="); accept != "s3pPLMBiTxaQ9kYGzzhZRbK+xOo=" {
+		t.Errorf("unexpected accept key %q", accept)
+	}
+}
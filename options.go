@@ -0,0 +1,215 @@
+package ritago
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Option configures a RitaClient created with New.
+type Option func(*RitaConfig)
+
+// New creates a new instance of RitaClient for the server at url, authenticating with apikey.
+// This is the preferred way of creating a client: optional settings are passed as options.
+//
+// Parameters:
+//   - url: The url of the Rita server.
+//   - apikey: The api key used to authenticate the requests.
+//   - opts: The options applied to the client configuration.
+//
+// Returns:
+//   - *RitaClient: A pointer to the newly created RitaClient instance.
+//
+// Example:
+//
+//	client := ritago.New("https://example.com", "your-api-key",
+//	    ritago.WithTimeout(5*time.Second),
+//	    ritago.WithRetry(3, 200*time.Millisecond),
+//	)
+func New(url, apikey string, opts ...Option) *RitaClient {
+	config := RitaConfig{
+		Url:    url,
+		ApiKey: apikey,
+	}
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	return NewRitaClient(&config)
+}
+
+// Clone returns a new client with the configuration of c, changed by opts, e.g. a variant
+// with a longer timeout for a batch job.
+//
+// The clone shares the connection pools of c unless opts change the http client or the
+// proxy. It has its own MaxConcurrentSends bound.
+//
+// Parameters:
+//   - opts: The options applied to a copy of the configuration of c.
+//
+// Returns:
+//   - *RitaClient: A pointer to the new RitaClient instance.
+//
+// Example:
+//
+//	batch := client.Clone(ritago.WithTimeout(time.Minute), ritago.WithRetry(5, time.Second))
+func (c *RitaClient) Clone(opts ...Option) *RitaClient {
+	config := c.config
+
+	for _, opt := range opts {
+		opt(&config)
+	}
+
+	clone := NewRitaClient(&config)
+
+	if config.HTTPClient == c.config.HTTPClient && strings.TrimSpace(config.ProxyURL) == strings.TrimSpace(c.config.ProxyURL) {
+		clone.httpClient.Transport = c.httpClient.Transport
+		clone.sseClient.Transport = c.sseClient.Transport
+	}
+
+	return clone
+}
+
+// WithTimeout limits the duration of the unary requests.
+func WithTimeout(timeout time.Duration) Option {
+	return func(config *RitaConfig) {
+		config.Timeout = timeout
+	}
+}
+
+// WithHTTPClient uses client for the unary requests.
+func WithHTTPClient(client *http.Client) Option {
+	return func(config *RitaConfig) {
+		config.HTTPClient = client
+	}
+}
+
+// WithLogger sends the diagnostics of the client to logger.
+func WithLogger(logger *slog.Logger) Option {
+	return func(config *RitaConfig) {
+		config.Logger = logger
+	}
+}
+
+// WithRetry retries a failed unary request up to maxRetries times, waiting backoff
+// before the first retry and doubling the delay on every attempt. Sends are only
+// retried when made with an idempotency key, see RitaConfig.MaxRetries.
+func WithRetry(maxRetries int, backoff time.Duration) Option {
+	return func(config *RitaConfig) {
+		config.MaxRetries = maxRetries
+		config.RetryBackoff = backoff
+	}
+}
+
+// WithCompression sets whether the unary requests ask for gzip encoded responses.
+// Compression is enabled by default.
+func WithCompression(enabled bool) Option {
+	return func(config *RitaConfig) {
+		config.DisableCompression = !enabled
+	}
+}
+
+// WithSubscriptionCompression sets whether the subscriptions ask for a gzip encoded stream.
+// Compression is disabled by default, see RitaConfig.SubscriptionCompression.
+func WithSubscriptionCompression(enabled bool) Option {
+	return func(config *RitaConfig) {
+		config.SubscriptionCompression = enabled
+	}
+}
+
+// WithAuth sends the api key in header, prefixed by scheme when it isn't empty.
+//
+// Example:
+//
+//	ritago.WithAuth("Authorization", "Bearer") // Authorization: Bearer <key>
+//	ritago.WithAuth("X-API-Key", "")           // X-API-Key: <key>
+func WithAuth(header, scheme string) Option {
+	return func(config *RitaConfig) {
+		config.AuthHeader = header
+		config.AuthScheme = scheme
+	}
+}
+
+// WithReconnect makes the subscriptions reconnect when their stream is lost, waiting
+// a jittered delay between min and max before each attempt. Zero bounds use the defaults.
+func WithReconnect(min, max time.Duration) Option {
+	return func(config *RitaConfig) {
+		config.Reconnect = true
+		config.ReconnectMinDelay = min
+		config.ReconnectMaxDelay = max
+	}
+}
+
+// WithReconnectDedupe sets whether a reconnected subscription drops a redelivery of the
+// last event it delivered, see RitaConfig.ReconnectDedupe.
+func WithReconnectDedupe(enabled bool) Option {
+	return func(config *RitaConfig) {
+		config.ReconnectDedupe = enabled
+	}
+}
+
+// WithProxy routes the unary requests and the subscriptions through the proxy at proxyUrl.
+func WithProxy(proxyUrl string) Option {
+	return func(config *RitaConfig) {
+		config.ProxyURL = proxyUrl
+	}
+}
+
+// WithMaxConcurrentSends bounds the unary requests in flight at once to max.
+func WithMaxConcurrentSends(max int) Option {
+	return func(config *RitaConfig) {
+		config.MaxConcurrentSends = max
+	}
+}
+
+// WithMaxFrameSize bounds the size of the frames read from the subscriptions to max bytes.
+func WithMaxFrameSize(max int) Option {
+	return func(config *RitaConfig) {
+		config.MaxFrameSize = max
+	}
+}
+
+// WithResponseHook calls hook after every request, see RitaConfig.ResponseHook.
+func WithResponseHook(hook func(method, channel string, status int, dur time.Duration)) Option {
+	return func(config *RitaConfig) {
+		config.ResponseHook = hook
+	}
+}
+
+// WithLatestEventsParam asks the server for the last events of a channel with the query
+// param name, see RitaConfig.LatestEventsParam.
+func WithLatestEventsParam(name string) Option {
+	return func(config *RitaConfig) {
+		config.LatestEventsParam = name
+	}
+}
+
+// WithPingPath sets the path of the route answering Ping, see RitaConfig.PingPath.
+func WithPingPath(path string) Option {
+	return func(config *RitaConfig) {
+		config.PingPath = path
+	}
+}
+
+// WithRequestDecorator calls decorate right before every request is sent.
+//
+// Example:
+//
+//	ritago.WithRequestDecorator(func(ctx context.Context, req *http.Request) {
+//	    otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(req.Header))
+//	})
+func WithRequestDecorator(decorate func(ctx context.Context, req *http.Request)) Option {
+	return func(config *RitaConfig) {
+		config.RequestDecorator = decorate
+	}
+}
+
+// WithAllowNullPayload lets SendEvent send data that marshals to the JSON null.
+func WithAllowNullPayload() Option {
+	return func(config *RitaConfig) {
+		config.AllowNullPayload = true
+	}
+}
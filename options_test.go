@@ -0,0 +1,97 @@
+package ritago
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestNewAppliesOptions(t *testing.T) {
+	httpClient := &http.Client{}
+
+	client := New(" http://localhost ", " key ",
+		WithHTTPClient(httpClient),
+		WithTimeout(3*time.Second),
+		WithRetry(2, time.Millisecond),
+	)
+
+	if client.server != "http://localhost" || client.apikey != "key" {
+		t.Errorf("unexpected server %q and apikey %q", client.server, client.apikey)
+	}
+
+	if client.httpClient.Timeout != 3*time.Second {
+		t.Errorf("expected timeout to be applied, got %v", client.httpClient.Timeout)
+	}
+
+	if httpClient.Timeout != 0 {
+		t.Errorf("the http client passed by the user must not be modified")
+	}
+
+	if client.maxRetries != 2 || client.retryBackoff != time.Millisecond {
+		t.Errorf("unexpected retry settings %d, %v", client.maxRetries, client.retryBackoff)
+	}
+}
+
+func TestRetryOnServerError(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&calls, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithRetry(2, time.Millisecond))
+
+	cursor, err := client.GetCursor("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if cursor != "1-0" || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("expected cursor 1-0 after 3 calls, got %q after %d", cursor, calls)
+	}
+
+	atomic.StoreInt32(&calls, -10)
+
+	if _, err := New(server.URL, "key").GetCursor("test"); err != UnknownError {
+		t.Errorf("expected UnknownError without retries, got %v", err)
+	}
+}
+
+func TestClone(t *testing.T) {
+	client := New("http://localhost", "key", WithTimeout(time.Second), WithMaxConcurrentSends(2))
+
+	clone := client.Clone(WithTimeout(time.Minute), WithRetry(3, time.Millisecond))
+
+	if clone.httpClient.Timeout != time.Minute || clone.maxRetries != 3 {
+		t.Errorf("expected the overrides to be applied, got %v and %d retries", clone.httpClient.Timeout, clone.maxRetries)
+	}
+
+	if client.httpClient.Timeout != time.Second || client.maxRetries != 0 {
+		t.Errorf("the original client must not be modified")
+	}
+
+	if clone.server != client.server || clone.apikey != client.apikey || cap(clone.sem) != 2 || clone.sem == client.sem {
+		t.Errorf("expected the configuration to be copied with a bound of its own")
+	}
+
+	if clone.sseClient.Transport != client.sseClient.Transport || clone.httpClient.Transport != client.httpClient.Transport {
+		t.Errorf("expected the transports to be shared")
+	}
+
+	proxied := client.Clone(WithProxy("http://proxy:3128"))
+	if proxied.sseClient.Transport == client.sseClient.Transport || proxied.httpClient.Transport == nil {
+		t.Errorf("expected a proxy override to get transports of its own")
+	}
+
+	if client.Clone(WithProxy("::")).configErr != ProxyUrlNotValid {
+		t.Errorf("expected the configuration of the clone to be validated")
+	}
+}
@@ -0,0 +1,130 @@
+package ritago
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// OrderedSender sends events to a channel one at a time, in the order Send was called,
+// so events published from concurrent goroutines reach the server in a strict order.
+//
+// Each send, retries included, completes before the next one starts. A failed send
+// doesn't stop the queue: the next event is sent after it, so callers needing a gap-free
+// stream must stop sending on error. Every sender of a channel created by the same client
+// shares one queue, whatever the case of the channel name, while the channels send in
+// parallel.
+type OrderedSender struct {
+	client  *RitaClient
+	channel string
+	queue   *orderedQueue
+}
+
+// orderedQueue is the send queue of a channel.
+type orderedQueue struct {
+	mu sync.Mutex
+	// tail is closed once the last queued send is done.
+	tail chan struct{}
+}
+
+// NewOrderedSender returns an OrderedSender sending the events to channel.
+//
+// Parameters:
+//   - channel: The name of the channel to which the events will be sent.
+//
+// Returns:
+//   - *OrderedSender: The sender of the channel.
+//
+// Example:
+//
+//	sender := client.NewOrderedSender("orders")
+//
+//	for _, change := range changes {
+//		if _, err := sender.Send(ctx, change); err != nil {
+//			return err
+//		}
+//	}
+func (c *RitaClient) NewOrderedSender(channel string) *OrderedSender {
+	return &OrderedSender{client: c, channel: channel, queue: c.orderedQueue(normalizeChannel(channel))}
+}
+
+// orderedQueue returns the send queue of channel, creating it on first use.
+func (c *RitaClient) orderedQueue(channel string) *orderedQueue {
+	c.orderedMu.Lock()
+	defer c.orderedMu.Unlock()
+
+	if c.ordered == nil {
+		c.ordered = map[string]*orderedQueue{}
+	}
+
+	queue, ok := c.ordered[channel]
+	if !ok {
+		tail := make(chan struct{})
+		close(tail)
+
+		queue = &orderedQueue{tail: tail}
+		c.ordered[channel] = queue
+	}
+
+	return queue
+}
+
+// Send queues the event and blocks until the server acknowledged it, after every event
+// queued before it. The data is validated and marshaled when queued, so a later change
+// to it isn't sent.
+//
+// Parameters:
+//   - ctx: The context of the send; when it is done while queued, the event is
+//     dropped from the queue without being sent.
+//   - data: The data to be sent as the event, as for SendEvent.
+//
+// Returns:
+//   - string: The event ID of the sent event.
+//   - error: The errors of SendEventContext, or the error of ctx when it is done while queued.
+func (s *OrderedSender) Send(ctx context.Context, data interface{}) (string, error) {
+	return s.send(ctx, data, http.Header{})
+}
+
+// SendWithKey queues the event like Send, attaching key as the Idempotency-Key header as
+// SendEventWithKey does, so a retry of the send can't publish the event twice.
+func (s *OrderedSender) SendWithKey(ctx context.Context, data interface{}, key string) (string, error) {
+	header := http.Header{}
+	if key = strings.TrimSpace(key); key != "" {
+		header.Set("Idempotency-Key", key)
+	}
+
+	return s.send(ctx, data, header)
+}
+
+func (s *OrderedSender) send(ctx context.Context, data interface{}, header http.Header) (string, error) {
+	if _, err := s.client.ensureCan(s.channel); err != nil {
+		return "", err
+	}
+
+	raw, err := s.client.marshalData(data)
+	if err != nil {
+		return "", err
+	}
+
+	s.queue.mu.Lock()
+	previous := s.queue.tail
+	done := make(chan struct{})
+	s.queue.tail = done
+	s.queue.mu.Unlock()
+
+	select {
+	case <-previous:
+	case <-ctx.Done():
+		// the sends queued after this one still wait for the previous one
+		go func() {
+			<-previous
+			close(done)
+		}()
+		return "", ctx.Err()
+	}
+	defer close(done)
+
+	return s.client.sendEvent(ctx, s.channel, json.RawMessage(raw), header)
+}
@@ -0,0 +1,209 @@
+package ritago
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestOrderedSender(t *testing.T) {
+	release := make(chan struct{})
+	var inFlight, maxInFlight int32
+
+	var mu sync.Mutex
+	var received []string
+	attempts := map[string]int{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		data := strings.Trim(string(body), `"`)
+
+		if strings.Contains(r.URL.Path, "/other") {
+			fmt.Fprint(w, `{"eventId":"other-0"}`)
+			return
+		}
+
+		if current := atomic.AddInt32(&inFlight, 1); current > atomic.LoadInt32(&maxInFlight) {
+			atomic.StoreInt32(&maxInFlight, current)
+		}
+		defer atomic.AddInt32(&inFlight, -1)
+
+		if data == "a" {
+			<-release
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		// b fails once and is retried before c is sent
+		attempts[data]++
+		if data == "b" && attempts[data] == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+
+		received = append(received, data)
+		fmt.Fprintf(w, `{"eventId":"%d-0"}`, len(received))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithRetry(2, time.Millisecond))
+	sender := client.NewOrderedSender("test")
+
+	ids := make([]string, 3)
+	var wg sync.WaitGroup
+	for i, data := range []string{"a", "b", "c"} {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			id, err := sender.SendWithKey(context.Background(), data, "key-"+data)
+			if err != nil {
+				t.Error(err)
+			}
+			ids[i] = id
+		}()
+		// let the send be queued before the next one
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := sender.Send(ctx, "dropped"); err != context.DeadlineExceeded {
+		t.Errorf("expected the queued send to give up with its context, got %v", err)
+	}
+
+	if id, err := client.NewOrderedSender("other").Send(context.Background(), "x"); err != nil || id != "other-0" {
+		t.Errorf("expected another channel not to wait, got %q %v", id, err)
+	}
+
+	close(release)
+	wg.Wait()
+
+	if _, err := sender.Send(context.Background(), "d"); err != nil {
+		t.Fatal(err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+
+	if fmt.Sprint(received) != "[a b c d]" || fmt.Sprint(ids) != "[1-0 2-0 3-0]" {
+		t.Errorf("expected the events in order, got %v with ids %v", received, ids)
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max != 1 {
+		t.Errorf("expected one send in flight at a time, got %d", max)
+	}
+}
+
+func TestOrderedSenderValidatesWhenQueued(t *testing.T) {
+	sender := New("http://localhost", "key").NewOrderedSender("test")
+
+	if _, err := sender.Send(context.Background(), nil); err != EmptyPayload {
+		t.Errorf("expected EmptyPayload, got %v", err)
+	}
+
+	if _, err := New("http://localhost", "key").NewOrderedSender(" ").Send(context.Background(), "data"); err != ChannelNotValid {
+		t.Errorf("expected ChannelNotValid, got %v", err)
+	}
+}
+
+func TestOrderedSendersShareChannelQueue(t *testing.T) {
+	release := make(chan struct{})
+
+	var mu sync.Mutex
+	var received []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		data := strings.Trim(string(body), `"`)
+
+		if data == "a" {
+			<-release
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, data)
+		fmt.Fprintf(w, `{"eventId":"%d-0"}`, len(received))
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key")
+	first := client.NewOrderedSender("Orders")
+	second := client.NewOrderedSender(" orders")
+
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		first.Send(context.Background(), "a")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	go func() {
+		defer wg.Done()
+		second.Send(context.Background(), "b")
+	}()
+	time.Sleep(10 * time.Millisecond)
+
+	mu.Lock()
+	if len(received) != 0 {
+		t.Errorf("expected the second sender to wait for the first, got %v", received)
+	}
+	mu.Unlock()
+
+	close(release)
+	wg.Wait()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(received) != "[a b]" {
+		t.Errorf("expected the events in order, got %v", received)
+	}
+}
+
+func TestOrderedSenderGoesOnAfterFailure(t *testing.T) {
+	var mu sync.Mutex
+	var received []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		data := strings.Trim(string(body), `"`)
+
+		mu.Lock()
+		defer mu.Unlock()
+
+		received = append(received, data)
+		if data == "b" {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		fmt.Fprintf(w, `{"eventId":"%d-0"}`, len(received))
+	}))
+	defer server.Close()
+
+	sender := New(server.URL, "key").NewOrderedSender("test")
+
+	sender.Send(context.Background(), "a")
+
+	// the failed send is reported and the next one is sent after it
+	if _, err := sender.Send(context.Background(), "b"); err != UnknownError {
+		t.Errorf("expected UnknownError, got %v", err)
+	}
+	sender.Send(context.Background(), "c")
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(received) != "[a b c]" {
+		t.Errorf("expected the queue to go on after b, got %v", received)
+	}
+}
@@ -0,0 +1,54 @@
+package ritago
+
+import (
+	"context"
+	"strconv"
+)
+
+// eventsPageSize is the number of events requested per page when reading a
+// channel incrementally.
+const eventsPageSize = 100
+
+// eachEvent reads the events of channel after since one page at a time,
+// calling fn for each of them until fn returns false or there are no more
+// events.
+//
+// Pages are requested with the limit query param, but a page shorter than the
+// limit doesn't end the read: the server may cap its pages lower, so reading
+// goes on until a page brings no new event, at the cost of a last request. A
+// server that ignores the limit returns every event in the first page and the
+// next request comes back empty. The event a page starts from is skipped, so
+// servers that treat eventId as inclusive don't deliver it twice.
+func (c *RitaClient) eachEvent(ctx context.Context, channel, since string, fn func(*RitaEvent) bool) error {
+	cursor := since
+	limit := map[string]string{"limit": strconv.Itoa(eventsPageSize)}
+
+	for {
+		page, err := c.getEvents(ctx, channel, cursor, limit)
+		if err != nil {
+			return err
+		}
+
+		next := cursor
+		delivered := 0
+
+		for i := range page {
+			if cursor != "" && page[i].Id == cursor {
+				continue
+			}
+
+			delivered++
+			next = page[i].Id
+
+			if !fn(&page[i]) {
+				return nil
+			}
+		}
+
+		if delivered == 0 {
+			return nil
+		}
+
+		cursor = next
+	}
+}
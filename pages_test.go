@@ -0,0 +1,203 @@
+package ritago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// newPagedServer serves total events with ids "1-0", "2-0"... honouring the
+// eventId and limit query params, and counts the requests it receives. Pages
+// hold at most maxPage events when it isn't zero, whatever the limit. The
+// cursor endpoint returns the id of the last event.
+func newPagedServer(t *testing.T, total, maxPage int, inclusive bool, requests *int) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+
+		if strings.HasSuffix(r.URL.Path, "/last") {
+			cursor := ""
+			if total > 0 {
+				cursor = fmt.Sprintf("%d-0", total)
+			}
+			json.NewEncoder(w).Encode(getCursorResponse{EventId: cursor})
+			return
+		}
+
+		start := 1
+		if eventId := r.URL.Query().Get("eventId"); eventId != "" {
+			fmt.Sscanf(eventId, "%d-0", &start)
+			if !inclusive {
+				start++
+			}
+		}
+
+		limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
+		if maxPage > 0 && (limit == 0 || limit > maxPage) {
+			limit = maxPage
+		}
+
+		events := []RitaEvent{}
+		for i := start; i <= total && (limit == 0 || len(events) < limit); i++ {
+			events = append(events, RitaEvent{Id: fmt.Sprintf("%d-0", i)})
+		}
+
+		json.NewEncoder(w).Encode(eventsResponse{Events: events})
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestGetLatestEvents(t *testing.T) {
+	cases := []struct {
+		total, n int
+		first    int
+	}{
+		{total: 250, n: 10, first: 241},
+		{total: 250, n: 100, first: 151},
+		{total: 5, n: 10, first: 1},
+		{total: 0, n: 10},
+		{total: 250, n: 0},
+	}
+
+	for _, tc := range cases {
+		requests := 0
+		server := newPagedServer(t, tc.total, 0, false, &requests)
+
+		events, err := New(server.URL, "key").GetLatestEvents("test", tc.n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		expected := tc.n
+		if tc.total < tc.n {
+			expected = tc.total
+		}
+
+		if len(events) != expected {
+			t.Fatalf("total=%d n=%d: expected %d events, got %d", tc.total, tc.n, expected, len(events))
+		}
+
+		for i, event := range events {
+			if event.Id != fmt.Sprintf("%d-0", tc.first+i) {
+				t.Errorf("total=%d n=%d: expected oldest first, got %s at %d", tc.total, tc.n, event.Id, i)
+			}
+		}
+	}
+}
+
+func TestEachEventBelowServerPageCap(t *testing.T) {
+	for _, inclusive := range []bool{false, true} {
+		requests := 0
+		server := newPagedServer(t, 250, 50, inclusive, &requests)
+
+		var ids []string
+		err := New(server.URL, "key").eachEvent(context.Background(), "test", "", func(event *RitaEvent) bool {
+			ids = append(ids, event.Id)
+			return true
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(ids) != 250 || ids[0] != "1-0" || ids[249] != "250-0" {
+			t.Errorf("inclusive=%v: expected the 250 events across pages of 50, got %d", inclusive, len(ids))
+		}
+
+		// pages of 50 new events, or 49 when each repeats its start, then one bringing nothing new
+		expected := 6
+		if inclusive {
+			expected = 7
+		}
+		if requests != expected {
+			t.Errorf("inclusive=%v: expected %d requests, got %d", inclusive, expected, requests)
+		}
+	}
+}
+
+func TestGetLatestEventsBelowServerPageCap(t *testing.T) {
+	server := newPagedServer(t, 250, 50, false, new(int))
+
+	events, err := New(server.URL, "key").GetLatestEvents("test", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 || events[0].Id != "248-0" || events[2].Id != "250-0" {
+		t.Errorf("expected the last 3 events, got %+v", events)
+	}
+}
+
+func TestGetLatestEventsTailNotFound(t *testing.T) {
+	base := newPagedServer(t, 250, 0, false, new(int))
+
+	// the tail is reported but the events stop before it, as when the channel is cleared meanwhile
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/last") {
+			json.NewEncoder(w).Encode(getCursorResponse{EventId: "300-0"})
+			return
+		}
+		base.Config.Handler.ServeHTTP(w, r)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key")
+
+	if events, err := client.GetLatestEvents("test", 3); err != TailNotFound || len(events) != 0 {
+		t.Errorf("expected TailNotFound, got %v %v", events, err)
+	}
+
+	if _, err := client.SubEventTail("test", 3); err != TailNotFound {
+		t.Errorf("expected SubEventTail to fail with TailNotFound, got %v", err)
+	}
+}
+
+func TestGetLatestEventsParam(t *testing.T) {
+	var queries []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+
+		last, _ := strconv.Atoi(r.URL.Query().Get("last"))
+		events := []RitaEvent{}
+		for i := 250 - last + 1; i <= 250; i++ {
+			events = append(events, RitaEvent{Id: fmt.Sprintf("%d-0", i)})
+		}
+		json.NewEncoder(w).Encode(eventsResponse{Events: events})
+	}))
+	defer server.Close()
+
+	events, err := New(server.URL, "key", WithLatestEventsParam("last")).GetLatestEvents("test", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 3 || events[0].Id != "248-0" || events[2].Id != "250-0" {
+		t.Errorf("expected the last 3 events, got %+v", events)
+	}
+
+	if len(queries) != 1 || queries[0] != "eventId=&last=3&sub=false" {
+		t.Errorf("expected a single request for the last events, got %v", queries)
+	}
+}
+
+func TestGetLatestEventsLargeN(t *testing.T) {
+	server := newPagedServer(t, 3, 0, false, new(int))
+	client := New(server.URL, "key")
+
+	for _, n := range []int{1e7, 1 << 40} {
+		events, err := client.GetLatestEvents("test", n)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if len(events) != 3 || cap(events) > 8 || events[0].Id != "1-0" || events[2].Id != "3-0" {
+			t.Errorf("n=%d: expected the 3 events without a buffer of n, got %d with capacity %d", n, len(events), cap(events))
+		}
+	}
+}
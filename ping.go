@@ -0,0 +1,112 @@
+package ritago
+
+import (
+	"context"
+	"net/http"
+	"time"
+)
+
+const defaultReadyInterval = time.Second
+
+// Ping checks that the server is reachable and accepts the apikey of the client, with a GET
+// request to the PingPath of the configuration, /v1/ping by default.
+//
+// Parameters:
+//   - ctx: The context of the request.
+//
+// Returns:
+//   - error: nil when the server answered with a success status; the error of the
+//     configuration, of the request or of the status otherwise.
+//
+// Example:
+//
+//	...
+//	if err := client.Ping(ctx); err != nil {
+//		log.Printf("rita is down: %v", err)
+//	}
+//	...
+func (c *RitaClient) Ping(ctx context.Context) error {
+	if err := c.ensureConfig(); err != nil {
+		return err
+	}
+
+	url, err := c.createUrl("", c.urlPing, nil)
+	if err != nil {
+		return err
+	}
+
+	status, _, err := c.do(ctx, "GET", "", url, nil, http.Header{})
+	if err != nil {
+		return err
+	}
+
+	if status < 200 || status > 299 {
+		return statusError(status)
+	}
+
+	return nil
+}
+
+// WaitForReady calls Ping every interval until the server answers, e.g. to block the
+// startup of an application until the server it depends on is up.
+//
+// Waiting stops early when Ping fails in a way another attempt can't fix: a configuration
+// error, or the server refusing the apikey with NotAuthorized or Forbidden. NotFound is
+// retried, as a gateway answers 404 until the server behind it is routed; a server without
+// the ping route answers 404 for good, so the wait then ends with ctx, returning NotFound.
+//
+// Parameters:
+//   - ctx: Bounds the wait.
+//   - interval: The delay between two attempts. Zero means one second.
+//
+// Returns:
+//   - error: nil once the server is ready; the error of the last attempt when ctx is done
+//     before, or the error of ctx when no attempt completed.
+//
+// Example:
+//
+//	...
+//	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+//	defer cancel()
+//
+//	if err := client.WaitForReady(ctx, 2*time.Second); err != nil {
+//		log.Fatalf("rita never came up: %v", err)
+//	}
+//	...
+func (c *RitaClient) WaitForReady(ctx context.Context, interval time.Duration) error {
+	if interval <= 0 {
+		interval = defaultReadyInterval
+	}
+
+	var lastErr error
+
+	for {
+		err := c.Ping(ctx)
+		if err == nil {
+			return nil
+		}
+
+		if ctx.Err() != nil {
+			if lastErr == nil {
+				return ctx.Err()
+			}
+			return lastErr
+		}
+
+		// compared with == since NotFound matches Forbidden with errors.Is
+		if c.ensureConfig() != nil || err == NotAuthorized || err == Forbidden {
+			return err
+		}
+
+		lastErr = err
+		c.logger.Debug("rita server not ready", "error", err)
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return lastErr
+		case <-timer.C:
+		}
+	}
+}
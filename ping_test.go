@@ -0,0 +1,102 @@
+package ritago
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestPing(t *testing.T) {
+	var path string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		if r.Header.Get("Authorization") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	}))
+	defer server.Close()
+
+	if err := New(server.URL, "key").Ping(context.Background()); err != nil || path != "/v1/ping" {
+		t.Errorf("expected a ping of /v1/ping, got %q %v", path, err)
+	}
+
+	if err := New(server.URL, "other").Ping(context.Background()); err != NotAuthorized {
+		t.Errorf("expected NotAuthorized, got %v", err)
+	}
+
+	if err := New(server.URL, "key", WithPingPath("healthz")).Ping(context.Background()); err != nil || path != "/healthz" {
+		t.Errorf("expected a ping of /healthz, got %q %v", path, err)
+	}
+
+	if err := New(server.URL, "").Ping(context.Background()); err != ApikeyNotConfig {
+		t.Errorf("expected ApikeyNotConfig, got %v", err)
+	}
+}
+
+func TestWaitForReady(t *testing.T) {
+	var pings int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&pings, 1) <= 2 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	if err := New(server.URL, "key").WaitForReady(context.Background(), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if pings := atomic.LoadInt32(&pings); pings != 3 {
+		t.Errorf("expected to ping until the server is up, got %d pings", pings)
+	}
+}
+
+func TestWaitForReadyReturnsLastError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+
+	if err := New(server.URL, "key").WaitForReady(ctx, 5*time.Millisecond); err != UnknownError {
+		t.Errorf("expected the error of the last ping, got %v", err)
+	}
+
+	var pings int32
+	refusing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&pings, 1)
+		w.WriteHeader(http.StatusForbidden)
+	}))
+	defer refusing.Close()
+
+	if err := New(refusing.URL, "key").WaitForReady(context.Background(), time.Millisecond); err != Forbidden || pings != 1 {
+		t.Errorf("expected to stop on Forbidden, got %v after %d pings", err, pings)
+	}
+}
+
+func TestWaitForReadyRetriesNotFound(t *testing.T) {
+	var pings int32
+
+	// a gateway answers 404 until the server behind it is routed
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&pings, 1) <= 2 {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	if err := New(server.URL, "key").WaitForReady(context.Background(), time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+
+	if pings := atomic.LoadInt32(&pings); pings != 3 {
+		t.Errorf("expected to ping until the server is routed, got %d pings", pings)
+	}
+}
@@ -0,0 +1,103 @@
+package ritago
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+const (
+	defaultReconnectMinDelay = 500 * time.Millisecond
+	defaultReconnectMaxDelay = 30 * time.Second
+)
+
+// reconnectPolicy bounds the delay between the attempts of a subscription to
+// open its stream again.
+type reconnectPolicy struct {
+	min time.Duration
+	max time.Duration
+	// dedupe drops the redelivery of the last event at the start of a reopened stream.
+	dedupe bool
+}
+
+func newReconnectPolicy(min, max time.Duration) *reconnectPolicy {
+	if min <= 0 {
+		min = defaultReconnectMinDelay
+	}
+	if max <= 0 {
+		max = defaultReconnectMaxDelay
+	}
+	if max < min {
+		max = min
+	}
+
+	return &reconnectPolicy{min: min, max: max}
+}
+
+// delay returns the wait before the given attempt, counted from 0, using full
+// jitter: a random delay between min and an exponentially growing ceiling,
+// capped at max, so clients dropped at the same time don't reconnect together.
+// The ceiling is twice base on the first attempt, so that one is jittered too,
+// base being the retry hint of the server when it sent one.
+func (p *reconnectPolicy) delay(base time.Duration, attempt int) time.Duration {
+	if base < p.min {
+		base = p.min
+	}
+
+	shift := attempt + 1
+
+	ceiling := p.max
+	if shift < 32 && base<<shift > 0 && base<<shift < p.max {
+		ceiling = base << shift
+	}
+
+	if ceiling <= p.min {
+		return p.min
+	}
+
+	return p.min + time.Duration(rand.Int64N(int64(ceiling-p.min)+1))
+}
+
+// redial opens the stream again from the last delivered event, waiting a
+// jittered delay between attempts. It reports false when the subscription
+// must end: it was closed, ctx is done, or the server refused the request.
+func (s *Subscription) redial(ctx context.Context) bool {
+	s.setState(Reconnecting)
+
+	for attempt := 0; ; attempt++ {
+		delay := s.reconnect.delay(s.retryHint, attempt)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-s.done:
+			timer.Stop()
+			return false
+		case <-ctx.Done():
+			timer.Stop()
+			return false
+		case <-timer.C:
+		}
+
+		stream, err := s.open(ctx, s.resumeId)
+		if err == nil {
+			if !s.setStream(stream) {
+				return false
+			}
+			s.seam = s.reconnect.dedupe && s.resumeId != ""
+			s.setState(Connected)
+			return true
+		}
+
+		if s.stopped(ctx) {
+			return false
+		}
+
+		if errors.Is(err, NotAuthorized) || errors.Is(err, Forbidden) || errors.Is(err, NotFound) {
+			s.sendError(err)
+			return false
+		}
+
+		s.logger.Warn("rita subscription reconnection failed", "error", err, "attempt", attempt+1, "delay", delay)
+	}
+}
@@ -0,0 +1,199 @@
+package ritago
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestReconnectDelayWithinBounds(t *testing.T) {
+	policy := newReconnectPolicy(100*time.Millisecond, 2*time.Second)
+
+	for attempt := 0; attempt < 40; attempt++ {
+		ceiling := 100 * time.Millisecond << (attempt + 1)
+		if attempt >= 4 {
+			ceiling = 2 * time.Second
+		}
+
+		seen := map[time.Duration]bool{}
+		for i := 0; i < 50; i++ {
+			delay := policy.delay(0, attempt)
+			if delay < 100*time.Millisecond || delay > ceiling {
+				t.Fatalf("attempt %d: delay %v out of [100ms, %v]", attempt, delay, ceiling)
+			}
+			seen[delay] = true
+		}
+
+		if len(seen) < 2 {
+			t.Errorf("attempt %d: expected jittered delays, got %v", attempt, seen)
+		}
+	}
+}
+
+func TestReconnectDelayUsesRetryHint(t *testing.T) {
+	policy := newReconnectPolicy(10*time.Millisecond, time.Minute)
+
+	for i := 0; i < 50; i++ {
+		delay := policy.delay(5*time.Second, 1)
+		if delay < 10*time.Millisecond || delay > 20*time.Second {
+			t.Fatalf("delay %v out of [10ms, 20s]", delay)
+		}
+	}
+
+	// a hint above the maximum is capped
+	for i := 0; i < 50; i++ {
+		if delay := policy.delay(time.Hour, 0); delay > time.Minute {
+			t.Fatalf("delay %v above maximum", delay)
+		}
+	}
+}
+
+func TestSubscriptionReconnectsFromLastEvent(t *testing.T) {
+	var mu sync.Mutex
+	var since []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		since = append(since, r.URL.Query().Get("eventId"))
+		connection := len(since)
+		mu.Unlock()
+
+		if connection > 3 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		// each connection delivers one event and drops
+		fmt.Fprintf(w, "retry: 1\ndata: {\"id\":\"%d-0\"}\n\n", connection)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithReconnect(time.Millisecond, 5*time.Millisecond))
+
+	sub, err := client.Subscribe(context.Background(), "test", "0-0")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for event := range sub.Events() {
+		ids = append(ids, event.Id)
+	}
+
+	if fmt.Sprint(ids) != "[1-0 2-0 3-0]" {
+		t.Errorf("unexpected events %v", ids)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(since) != "[0-0 1-0 2-0 3-0]" {
+		t.Errorf("expected to resume from the last event, got %v", since)
+	}
+
+	var states []ConnectionState
+	for state := range sub.Status() {
+		states = append(states, state)
+	}
+
+	if fmt.Sprint(states) != fmt.Sprint([]ConnectionState{Connecting, Connected, Reconnecting, Connected, Reconnecting, Connected, Reconnecting, Closed}) {
+		t.Errorf("unexpected states %v", states)
+	}
+
+	if err := <-sub.Errors(); err != Forbidden {
+		t.Errorf("expected the refused reconnection to be reported, got %v", err)
+	}
+}
+
+func TestSubscriptionReconnectsAfterOversizedFrame(t *testing.T) {
+	var connections int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch atomic.AddInt32(&connections, 1) {
+		case 1:
+			fmt.Fprintf(w, "retry: 1\ndata: {\"id\":\"1-0\"}\n\ndata: {\"id\":\"2-0\",\"data\":\"%s\"}\n\n", strings.Repeat("x", 4096))
+		case 2:
+			fmt.Fprintf(w, "data: {\"id\":\"%s\"}\n\n", r.URL.Query().Get("eventId"))
+		default:
+			w.WriteHeader(http.StatusForbidden)
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithReconnect(time.Millisecond, 5*time.Millisecond), WithMaxFrameSize(1024))
+
+	sub, err := client.Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for event := range sub.Events() {
+		ids = append(ids, event.Id)
+	}
+
+	if fmt.Sprint(ids) != "[1-0 1-0]" {
+		t.Errorf("expected to skip the oversized frame and resume from 1-0, got %v", ids)
+	}
+
+	var errs []error
+	for err := range sub.Errors() {
+		errs = append(errs, err)
+	}
+
+	if len(errs) != 2 || errs[0] != FrameTooLarge || errs[1] != Forbidden {
+		t.Errorf("expected the oversized frame to be reported, got %v", errs)
+	}
+}
+
+func TestSubscriptionReconnectDedupe(t *testing.T) {
+	for _, dedupe := range []bool{true, false} {
+		var connections int32
+
+		// the server resumes inclusively: each connection starts with the event it resumes from
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			connection := atomic.AddInt32(&connections, 1)
+			if connection > 3 {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+
+			fmt.Fprint(w, "retry: 1\n\n")
+			if eventId := r.URL.Query().Get("eventId"); eventId != "" {
+				fmt.Fprintf(w, "data: {\"id\":\"%s\"}\n\n", eventId)
+			}
+			fmt.Fprintf(w, "data: {\"id\":\"%d-0\"}\n\n", connection)
+			if connection == 1 {
+				// a duplicate within the stream isn't at the boundary
+				fmt.Fprintf(w, "data: {\"id\":\"%d-0\"}\n\n", connection)
+			}
+		}))
+
+		client := New(server.URL, "key", WithReconnect(time.Millisecond, 5*time.Millisecond), WithReconnectDedupe(dedupe))
+
+		sub, err := client.Subscribe(context.Background(), "test", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		var ids []string
+		for event := range sub.Events() {
+			ids = append(ids, event.Id)
+		}
+		server.Close()
+
+		expected := "[1-0 1-0 1-0 2-0 2-0 3-0]"
+		if dedupe {
+			expected = "[1-0 1-0 2-0 3-0]"
+		}
+
+		if fmt.Sprint(ids) != expected {
+			t.Errorf("dedupe %v: expected %s, got %v", dedupe, expected, ids)
+		}
+	}
+}
@@ -0,0 +1,229 @@
+package ritago
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultRetryBackoff = 200 * time.Millisecond
+
+	// defaultCursorsConcurrency bounds the requests in flight of a GetCursors batch.
+	defaultCursorsConcurrency = 8
+
+	// defaultMaxFrameSize bounds the frames of the subscriptions when MaxFrameSize is zero.
+	defaultMaxFrameSize = 1 << 20
+
+	// createdAtHeader carries the time of an event sent with SendEventAt.
+	createdAtHeader = "X-Created-At"
+)
+
+// do sends a unary request and returns the status code and the body of the
+// response. Failed attempts are retried up to maxRetries times when the
+// server could not be reached or answered with a 5xx/429 status. A POST is
+// only retried with an Idempotency-Key, as a failed attempt may still have
+// created the event and a retry would publish it twice.
+func (c *RitaClient) do(ctx context.Context, method, channel, url string, body []byte, header http.Header) (int, []byte, error) {
+	maxRetries := c.maxRetries
+	if method == "POST" && header.Get("Idempotency-Key") == "" {
+		maxRetries = 0
+	}
+
+	for attempt := 0; ; attempt++ {
+		status, respBody, err := c.doOnce(ctx, method, channel, url, body, header)
+
+		if attempt >= maxRetries || !shouldRetry(ctx, status, err) {
+			return status, respBody, err
+		}
+
+		delay := c.retryBackoff << attempt
+		c.logger.Warn("rita request failed, retrying",
+			"method", method, "url", url, "status", status, "error", err, "attempt", attempt+1, "delay", delay)
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return 0, nil, ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
+
+func (c *RitaClient) doOnce(ctx context.Context, method, channel, url string, body []byte, header http.Header) (int, []byte, error) {
+	if c.sem != nil {
+		select {
+		case c.sem <- struct{}{}:
+			defer func() { <-c.sem }()
+		case <-ctx.Done():
+			return 0, nil, ctx.Err()
+		}
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+
+	c.logger.Debug("rita request", "method", method, "url", url)
+
+	req, err := http.NewRequestWithContext(ctx, method, url, reader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	c.setAuth(req)
+
+	// setting Accept-Encoding ourselves turns off the transparent decompression of
+	// the transport, so it behaves the same whatever http client the user passed
+	if c.disableCompression {
+		req.Header.Set("Accept-Encoding", "identity")
+	} else {
+		req.Header.Set("Accept-Encoding", "gzip")
+	}
+
+	c.decorateRequest(ctx, req)
+
+	// reported once the body is read and closed, with a zero status when no response arrived
+	start := time.Now()
+	status := 0
+	defer func() { c.observe(method, channel, status, start) }()
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, nil, err
+	}
+	defer resp.Body.Close()
+	status = resp.StatusCode
+
+	var bodyReader io.Reader = resp.Body
+	if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+		gzipReader, err := gzip.NewReader(resp.Body)
+		if err != nil {
+			return 0, nil, err
+		}
+		defer gzipReader.Close()
+		bodyReader = gzipReader
+	}
+
+	respBody, err := io.ReadAll(bodyReader)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	return resp.StatusCode, respBody, nil
+}
+
+// observe reports a request sent to channel to the ResponseHook.
+func (c *RitaClient) observe(method, channel string, status int, start time.Time) {
+	if c.responseHook != nil {
+		c.responseHook(method, channel, status, time.Since(start))
+	}
+}
+
+func shouldRetry(ctx context.Context, status int, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+
+	if err != nil {
+		return !errors.Is(err, context.Canceled) && !errors.Is(err, context.DeadlineExceeded)
+	}
+
+	return status >= 500 || status == http.StatusTooManyRequests
+}
+
+// sseTransport derives the transport of the subscriptions from base, so they
+// share the proxy, TLS and dialer settings of the unary requests while keeping
+// the transparent compression of the transport off. A base that isn't an
+// *http.Transport can't be cloned and is used as is; either way the subscription
+// chooses its encoding through its headers and decompresses the stream itself.
+func sseTransport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base
+	}
+
+	transport = transport.Clone()
+	transport.DisableCompression = true
+
+	return transport
+}
+
+// parseProxyUrl validates the proxy url of the configuration.
+func parseProxyUrl(proxyUrl string) (*url.URL, error) {
+	u, err := url.Parse(proxyUrl)
+	if err != nil || u.Host == "" {
+		return nil, ProxyUrlNotValid
+	}
+
+	switch u.Scheme {
+	case "http", "https", "socks5", "socks5h":
+		return u, nil
+	default:
+		return nil, ProxyUrlNotValid
+	}
+}
+
+// withProxy returns a clone of base routing the requests through proxy. It
+// reports false when base isn't an *http.Transport and can't be configured.
+func withProxy(base http.RoundTripper, proxy *url.URL) (http.RoundTripper, bool) {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	transport, ok := base.(*http.Transport)
+	if !ok {
+		return base, false
+	}
+
+	transport = transport.Clone()
+	transport.Proxy = http.ProxyURL(proxy)
+
+	return transport, true
+}
+
+// rejectsCreatedAt reports whether the body of a rejected send points at the
+// time of the event, with a {"field": "createdAt"} or
+// {"code": "created_at_not_accepted"} payload.
+func rejectsCreatedAt(body []byte) bool {
+	var payload struct {
+		Field string `json:"field"`
+		Code  string `json:"code"`
+	}
+
+	if json.Unmarshal(body, &payload) != nil {
+		return false
+	}
+
+	return strings.EqualFold(payload.Field, "createdAt") || payload.Code == "created_at_not_accepted"
+}
+
+// statusError maps a non successful status code to its ritaError.
+func statusError(status int) error {
+	switch status {
+	case 401:
+		return NotAuthorized
+	case 403:
+		return Forbidden
+	case 404:
+		return NotFound
+	default:
+		return UnknownError
+	}
+}
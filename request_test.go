@@ -0,0 +1,754 @@
+package ritago
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestGetEventsSinceDecompressesGzip(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			fmt.Fprint(w, `{"events":[]}`)
+			return
+		}
+
+		w.Header().Set("Content-Encoding", "gzip")
+		gz := gzip.NewWriter(w)
+		defer gz.Close()
+		fmt.Fprint(gz, `{"events":[{"id":"1-0","data":{"key":"value"}},{"id":"2-0"}]}`)
+	}))
+	defer server.Close()
+
+	events, err := New(server.URL, "key").GetEventsSince("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[0].Id != "1-0" || events[1].Id != "2-0" {
+		t.Errorf("unexpected events %+v", events)
+	}
+}
+
+func TestCompressionOptOut(t *testing.T) {
+	var acceptEncoding string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		acceptEncoding = r.Header.Get("Accept-Encoding")
+		fmt.Fprint(w, `{"events":[]}`)
+	}))
+	defer server.Close()
+
+	if _, err := New(server.URL, "key", WithCompression(false)).GetEventsSince("test", ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if acceptEncoding != "identity" {
+		t.Errorf("expected identity encoding, got %q", acceptEncoding)
+	}
+}
+
+func TestSendEventWithKeyReusesKeyOnRetry(t *testing.T) {
+	var keys []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		keys = append(keys, r.Header.Get("Idempotency-Key"))
+		if len(keys) == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithRetry(1, time.Millisecond))
+
+	if _, err := client.SendEventWithKey("test", map[string]string{"a": "b"}, "order-1"); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(keys) != 2 || keys[0] != "order-1" || keys[1] != "order-1" {
+		t.Errorf("expected the key on both attempts, got %q", keys)
+	}
+}
+
+func TestSendEventWithEmptyKeySendsNoHeader(t *testing.T) {
+	sent := true
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, sent = r.Header["Idempotency-Key"]
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+
+	if _, err := New(server.URL, "key").SendEventWithKey("test", map[string]string{"a": "b"}, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	if sent {
+		t.Errorf("expected no Idempotency-Key header for an empty key")
+	}
+}
+
+func TestAuthHeaderAndScheme(t *testing.T) {
+	cases := []struct {
+		opts   []Option
+		header string
+		value  string
+	}{
+		{header: "Authorization", value: "key"},
+		{opts: []Option{WithAuth("", "Bearer")}, header: "Authorization", value: "Bearer key"},
+		{opts: []Option{WithAuth("X-API-Key", "")}, header: "X-API-Key", value: "key"},
+	}
+
+	for _, tc := range cases {
+		var values []string
+
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			values = append(values, r.Header.Get(tc.header))
+			if r.URL.Query().Get("sub") == "true" {
+				return
+			}
+			fmt.Fprint(w, `{"eventId":"1-0","events":[]}`)
+		}))
+
+		client := New(server.URL, "key", tc.opts...)
+		client.GetCursor("test")
+		client.SendEvent("test", map[string]string{"a": "b"})
+		client.GetEventsSince("test", "")
+		if sub, err := client.Subscribe(context.Background(), "test", ""); err == nil {
+			for range sub.Events() {
+			}
+		}
+
+		server.Close()
+
+		if len(values) != 4 {
+			t.Fatalf("expected 4 requests, got %d", len(values))
+		}
+
+		for _, value := range values {
+			if value != tc.value {
+				t.Errorf("expected %s: %q, got %q", tc.header, tc.value, value)
+			}
+		}
+	}
+}
+
+func TestTrimAndClearChannel(t *testing.T) {
+	var requests []string
+	status := http.StatusNoContent
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r.Method+" "+r.URL.Path+"?"+r.URL.RawQuery)
+		w.WriteHeader(status)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key")
+
+	if err := client.TrimChannel("Test", "10-0"); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := client.ClearChannel("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"DELETE /v1/event/test?before=10-0", "DELETE /v1/event/test?"}
+	if fmt.Sprint(requests) != fmt.Sprint(expected) {
+		t.Errorf("expected requests %q, got %q", expected, requests)
+	}
+
+	if err := client.TrimChannel("test", " "); err != EventIdNotValid {
+		t.Errorf("expected EventIdNotValid, got %v", err)
+	}
+
+	status = http.StatusNotFound
+	if err := client.ClearChannel("test"); err != NotFound {
+		t.Errorf("expected NotFound for 404, got %v", err)
+	}
+}
+
+func TestBuildURL(t *testing.T) {
+	client := New("https://example.com", "key")
+
+	cases := []struct {
+		kind   string
+		params map[string]string
+		url    string
+	}{
+		{UrlKindSend, nil, "https://example.com/v1/event/test"},
+		{UrlKindSub, map[string]string{"sub": "true", "eventId": "1-0"}, "https://example.com/v1/event/test?eventId=1-0&sub=true"},
+		{UrlKindCursor, nil, "https://example.com/v1/event/test/last"},
+		{UrlKindDelete, map[string]string{"before": "1-0"}, "https://example.com/v1/event/test?before=1-0"},
+	}
+
+	for _, tc := range cases {
+		url, err := client.BuildURL(" Test ", tc.kind, tc.params)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if url != tc.url {
+			t.Errorf("%s: expected %q, got %q", tc.kind, tc.url, url)
+		}
+	}
+
+	if _, err := client.BuildURL("test", "other", nil); err == nil {
+		t.Errorf("expected an error for an unknown kind")
+	}
+
+	if _, err := client.BuildURL("", UrlKindSend, nil); err != ChannelNotValid {
+		t.Errorf("expected ChannelNotValid, got %v", err)
+	}
+}
+
+func TestRequestUrlIsLogged(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+
+	var logs bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&logs, &slog.HandlerOptions{Level: slog.LevelDebug}))
+
+	if _, err := New(server.URL, "key", WithLogger(logger)).GetCursor("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if !strings.Contains(logs.String(), server.URL+"/v1/event/test/last") {
+		t.Errorf("expected the request url in the logs, got %q", logs.String())
+	}
+}
+
+func TestProxyURL(t *testing.T) {
+	var proxied []string
+
+	// the proxy answers in place of the server it is asked to reach
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		proxied = append(proxied, r.URL.String())
+		if r.URL.Query().Get("sub") == "true" {
+			fmt.Fprint(w, "data: {\"id\":\"1-0\"}\n\n")
+			return
+		}
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer proxy.Close()
+
+	client := New("http://rita.invalid", "key", WithProxy(proxy.URL))
+
+	if _, err := client.GetCursor("test"); err != nil {
+		t.Fatal(err)
+	}
+
+	sub, err := client.Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+
+	expected := []string{
+		"http://rita.invalid/v1/event/test/last",
+		"http://rita.invalid/v1/event/test?eventId=&sub=true",
+	}
+	if fmt.Sprint(proxied) != fmt.Sprint(expected) {
+		t.Errorf("expected %q through the proxy, got %q", expected, proxied)
+	}
+}
+
+func TestProxyURLNotValid(t *testing.T) {
+	for _, proxyUrl := range []string{"://bad", "ftp://proxy:21", "proxy:8080"} {
+		client := New("http://rita.invalid", "key", WithProxy(proxyUrl))
+
+		if _, err := client.GetCursor("test"); err != ProxyUrlNotValid {
+			t.Errorf("%q: expected ProxyUrlNotValid, got %v", proxyUrl, err)
+		}
+	}
+}
+
+func TestMaxConcurrentSends(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithMaxConcurrentSends(3))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 30; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.SendEvent("test", map[string]int{"i": 1}); err != nil {
+				t.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if max := atomic.LoadInt32(&maxInFlight); max > 3 || max == 0 {
+		t.Errorf("expected at most 3 sends in flight, got %d", max)
+	}
+}
+
+func TestMaxConcurrentSendsRespectsContext(t *testing.T) {
+	release := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-release
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+	defer close(release)
+
+	client := New(server.URL, "key", WithMaxConcurrentSends(1))
+
+	go client.SendEvent("test", "first")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	if _, err := client.SendEventContext(ctx, "test", "second"); err != context.DeadlineExceeded {
+		t.Errorf("expected the queued send to give up with its context, got %v", err)
+	}
+}
+
+func TestStatusErrors(t *testing.T) {
+	expected := map[int]error{
+		http.StatusUnauthorized:        NotAuthorized,
+		http.StatusForbidden:           Forbidden,
+		http.StatusNotFound:            NotFound,
+		http.StatusInternalServerError: UnknownError,
+	}
+
+	for status, expectedErr := range expected {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(status)
+		}))
+
+		client := New(server.URL, "key")
+
+		_, cursorErr := client.GetCursor("test")
+		_, sendErr := client.SendEvent("test", "data")
+		_, eventsErr := client.GetEventsSince("test", "")
+		_, subErr := client.Subscribe(context.Background(), "test", "")
+
+		server.Close()
+
+		for i, err := range []error{cursorErr, sendErr, eventsErr, subErr} {
+			if err != expectedErr {
+				t.Errorf("status %d, method %d: expected %v, got %v", status, i, expectedErr, err)
+			}
+		}
+	}
+}
+
+type traceKey struct{}
+
+func TestRequestDecorator(t *testing.T) {
+	var traceparents []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparents = append(traceparents, r.Header.Get("traceparent"))
+		if r.URL.Query().Get("sub") == "true" {
+			return
+		}
+		fmt.Fprint(w, `{"eventId":"1-0","events":[]}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithRequestDecorator(func(ctx context.Context, req *http.Request) {
+		if trace, ok := ctx.Value(traceKey{}).(string); ok {
+			req.Header.Set("traceparent", trace)
+		}
+	}))
+
+	ctx := context.WithValue(context.Background(), traceKey{}, "00-trace-span-01")
+
+	client.GetCursorContext(ctx, "test")
+	client.SendEventContext(ctx, "test", "data")
+	client.GetEventsSinceContext(ctx, "test", "")
+	if sub, err := client.Subscribe(ctx, "test", ""); err == nil {
+		for range sub.Events() {
+		}
+	}
+
+	if len(traceparents) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(traceparents))
+	}
+
+	for _, traceparent := range traceparents {
+		if traceparent != "00-trace-span-01" {
+			t.Errorf("expected the traceparent from the context, got %q", traceparent)
+		}
+	}
+
+	// without a decorator nothing is added
+	if _, err := New(server.URL, "key").GetCursorContext(ctx, "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	if traceparents[4] != "" {
+		t.Errorf("expected no traceparent without decorator, got %q", traceparents[4])
+	}
+}
+
+func TestSendEventRejectsNull(t *testing.T) {
+	var bodies []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		bodies = append(bodies, string(body))
+		fmt.Fprint(w, `{"eventId":"1-0"}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key")
+
+	var nilMap map[string]string
+	var nilPointer *struct{}
+
+	for _, data := range []interface{}{nil, nilMap, nilPointer} {
+		if _, err := client.SendEvent("test", data); err != EmptyPayload {
+			t.Errorf("%#v: expected EmptyPayload, got %v", data, err)
+		}
+	}
+
+	for _, data := range []interface{}{map[string]string{}, []int{}} {
+		if _, err := client.SendEvent("test", data); err != nil {
+			t.Errorf("%#v: expected empty collections to be sent, got %v", data, err)
+		}
+	}
+
+	if _, err := New(server.URL, "key", WithAllowNullPayload()).SendEvent("test", nil); err != nil {
+		t.Errorf("expected null to be sent when allowed, got %v", err)
+	}
+
+	if fmt.Sprint(bodies) != "[{} [] null]" {
+		t.Errorf("unexpected bodies %q", bodies)
+	}
+}
+
+func TestValidateEvent(t *testing.T) {
+	// the server is never reached
+	client := New("http://rita.invalid", "key")
+
+	cases := []struct {
+		channel string
+		data    interface{}
+		err     error
+	}{
+		{"test", map[string]string{"key": "value"}, nil},
+		{" ", map[string]string{"key": "value"}, ChannelNotValid},
+		{"test", make(chan int), JsonNotValid},
+		{"test", nil, EmptyPayload},
+	}
+
+	for _, tc := range cases {
+		if err := client.ValidateEvent(tc.channel, tc.data); err != tc.err {
+			t.Errorf("%q %#v: expected %v, got %v", tc.channel, tc.data, tc.err, err)
+		}
+	}
+
+	if err := New("", "key").ValidateEvent("test", "data"); err != ServerNotConfig {
+		t.Errorf("expected ServerNotConfig, got %v", err)
+	}
+}
+
+func TestGetCursors(t *testing.T) {
+	var inFlight, maxInFlight int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+
+		for {
+			max := atomic.LoadInt32(&maxInFlight)
+			if current <= max || atomic.CompareAndSwapInt32(&maxInFlight, max, current) {
+				break
+			}
+		}
+
+		time.Sleep(5 * time.Millisecond)
+
+		switch {
+		case strings.Contains(r.URL.Path, "/secret/"):
+			w.WriteHeader(http.StatusForbidden)
+		case strings.Contains(r.URL.Path, "/missing/"):
+			w.WriteHeader(http.StatusNotFound)
+		default:
+			fmt.Fprintf(w, `{"eventId":"%s-0"}`, strings.Split(r.URL.Path, "/")[3])
+		}
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithMaxConcurrentSends(2))
+
+	channels := []string{"a", "b", "secret", "c", "missing", "a"}
+
+	cursors, err := client.GetCursors(channels)
+
+	if len(cursors) != 3 || cursors["a"] != "a-0" || cursors["b"] != "b-0" || cursors["c"] != "c-0" {
+		t.Errorf("unexpected cursors %v", cursors)
+	}
+
+	failed, ok := err.(ChannelErrors)
+	if !ok {
+		t.Fatalf("expected ChannelErrors, got %T %v", err, err)
+	}
+	if len(failed) != 2 || failed["secret"] != Forbidden || failed["missing"] != NotFound {
+		t.Errorf("unexpected errors %v", failed)
+	}
+	if !errors.Is(err, Forbidden) {
+		t.Errorf("expected errors.Is to match the error of a channel")
+	}
+	if err.Error() != "2 channels failed: missing: not found; secret: forbidden" {
+		t.Errorf("unexpected message %q", err.Error())
+	}
+
+	if max := atomic.LoadInt32(&maxInFlight); max > 2 || max == 0 {
+		t.Errorf("expected at most 2 requests in flight, got %d", max)
+	}
+
+	cursors, err = client.GetCursors([]string{"a", "b"})
+	if err != nil || len(cursors) != 2 {
+		t.Errorf("expected no error, got %v %v", cursors, err)
+	}
+}
+
+func TestGetCursorsContext(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithRetry(0, 0))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+
+	cursors, err := client.GetCursorsContext(ctx, []string{"a", "b"})
+	if len(cursors) != 0 || !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the batch to give up with its context, got %v %v", cursors, err)
+	}
+}
+
+func TestResponseHook(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.URL.Query().Get("sub") == "true":
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"1-0\"}\n\n")
+		case r.Method == "DELETE":
+			w.WriteHeader(http.StatusForbidden)
+		default:
+			fmt.Fprint(w, `{"eventId":"1-0","events":[]}`)
+		}
+	}))
+
+	type call struct {
+		method  string
+		channel string
+		status  int
+	}
+
+	var mu sync.Mutex
+	var calls []call
+
+	client := New(server.URL, "key", WithResponseHook(func(method, channel string, status int, dur time.Duration) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if dur <= 0 {
+			t.Errorf("%s %s: expected a duration, got %v", method, channel, dur)
+		}
+		calls = append(calls, call{method, channel, status})
+	}))
+
+	client.GetCursor("Cursor")
+	client.SendEvent("send", "data")
+	client.GetEventsSince("events", "")
+	client.ClearChannel("clear")
+
+	sub, err := client.Subscribe(context.Background(), "sub", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for range sub.Events() {
+	}
+
+	server.Close()
+	client.GetCursor("down")
+
+	expected := []call{
+		{"GET", "cursor", 200},
+		{"POST", "send", 200},
+		{"GET", "events", 200},
+		{"DELETE", "clear", 403},
+		{"GET", "sub", 200},
+		{"GET", "down", 0},
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if fmt.Sprint(calls) != fmt.Sprint(expected) {
+		t.Errorf("expected %v, got %v", expected, calls)
+	}
+
+	// without a hook nothing breaks
+	if _, err := New(server.URL, "key").GetCursor("test"); err == nil {
+		t.Errorf("expected the closed server to fail the request")
+	}
+}
+
+func TestSendEventAt(t *testing.T) {
+	var mu sync.Mutex
+	var stored []map[string]interface{}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		if r.Method == "GET" {
+			json.NewEncoder(w).Encode(map[string]interface{}{"events": stored})
+			return
+		}
+
+		createdAt := r.Header.Get("X-Created-At")
+		if createdAt == "" {
+			createdAt = time.Now().UTC().Format(time.RFC3339Nano)
+		} else if _, err := time.Parse(time.RFC3339Nano, createdAt); err != nil || strings.HasPrefix(createdAt, "1900") {
+			w.WriteHeader(http.StatusUnprocessableEntity)
+			fmt.Fprint(w, `{"error":"too old","field":"createdAt"}`)
+			return
+		}
+
+		var data interface{}
+		json.NewDecoder(r.Body).Decode(&data)
+
+		if data == "invalid" {
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"error":"invalid payload","field":"data"}`)
+			return
+		}
+
+		id := fmt.Sprintf("%d-0", len(stored)+1)
+		stored = append(stored, map[string]interface{}{"id": id, "createdAt": createdAt, "data": data})
+		fmt.Fprintf(w, `{"eventId":"%s"}`, id)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key")
+
+	createdAt := time.Date(2021, 3, 4, 5, 6, 7, 890, time.FixedZone("CET", 3600))
+
+	if _, err := client.SendEventAt("test", "backfilled", createdAt); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.SendEventAt("test", "now", time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := client.GetEventsSince("test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || !events[0].CreatedAt.Equal(createdAt) || events[0].Data != "backfilled" {
+		t.Fatalf("expected the time to round-trip, got %+v", events)
+	}
+	if time.Since(events[1].CreatedAt) > time.Minute {
+		t.Errorf("expected a zero time to be sent as now, got %v", events[1].CreatedAt)
+	}
+
+	if _, err := client.SendEventAt("test", "old", time.Date(1900, 1, 1, 0, 0, 0, 0, time.UTC)); err != CreatedAtNotAccepted {
+		t.Errorf("expected CreatedAtNotAccepted, got %v", err)
+	}
+
+	if _, err := client.SendEventAt("test", "invalid", createdAt); err != UnknownError {
+		t.Errorf("expected a rejection of the payload not to blame the time, got %v", err)
+	}
+}
+
+func TestGetEventsSinceWith(t *testing.T) {
+	var rawQuery string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rawQuery = r.URL.RawQuery
+		fmt.Fprint(w, `{"events":[]}`)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key")
+
+	_, err := client.GetEventsSinceWith("test", "5-0", map[string]string{
+		"type":    "a&b=c d",
+		"key":     "ü/?",
+		"sub":     "true",
+		"eventId": "0-0",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if rawQuery != "eventId=5-0&key=%C3%BC%2F%3F&sub=false&type=a%26b%3Dc+d" {
+		t.Errorf("unexpected query %q", rawQuery)
+	}
+
+	if _, err := client.GetEventsSinceWith("test", "", map[string]string{"eventId": "0-0"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if rawQuery != "eventId=&sub=false" {
+		t.Errorf("expected the reserved params to be kept, got %q", rawQuery)
+	}
+}
+
+func TestSendEventNotRetriedWithoutKey(t *testing.T) {
+	var calls int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithRetry(2, time.Millisecond))
+
+	if _, err := client.SendEvent("test", "data"); err != UnknownError || atomic.LoadInt32(&calls) != 1 {
+		t.Errorf("expected a single attempt, got %v after %d calls", err, calls)
+	}
+
+	atomic.StoreInt32(&calls, 0)
+	if err := client.ClearChannel("test"); err != UnknownError || atomic.LoadInt32(&calls) != 3 {
+		t.Errorf("expected a DELETE to be retried, got %v after %d calls", err, calls)
+	}
+}
@@ -1,30 +1,77 @@
 package ritago
 
 import (
-	"bufio"
-	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
+	"log/slog"
 	"net/http"
 	"net/url"
 	"regexp"
+	"strconv"
 	"strings"
+	"sync"
+	"time"
 )
 
 type RitaClient struct {
 	urlEventSend string
 	urlEventSub  string
 	urlGetCursor string
+	urlEventDel  string
+	urlEventWS   string
+	urlPing      string
 
 	server string
 	apikey string
+
+	authHeader string
+	authScheme string
+
+	httpClient *http.Client
+	sseClient  *http.Client
+	logger     *slog.Logger
+
+	maxRetries   int
+	retryBackoff time.Duration
+
+	disableCompression      bool
+	subscriptionCompression bool
+	allowNullPayload        bool
+
+	// latestEventsParam is the query param asking the server for the last events; empty when unsupported.
+	latestEventsParam string
+
+	// maxFrameSize bounds the frames of the subscriptions; zero when unbounded.
+	maxFrameSize int
+
+	reconnect *reconnectPolicy
+
+	decorate     func(ctx context.Context, req *http.Request)
+	responseHook func(method, channel string, status int, dur time.Duration)
+
+	// sem bounds the unary requests in flight; nil when unbounded.
+	sem chan struct{}
+
+	// ordered holds the send queue of each channel of the OrderedSenders, guarded by orderedMu.
+	ordered   map[string]*orderedQueue
+	orderedMu sync.Mutex
+
+	// configErr is returned by every request when the configuration is not valid.
+	configErr error
+
+	// config is the configuration the client was created with, copied by Clone.
+	config RitaConfig
 }
 
 const LAST_EVENT = "$"
 
 // NewRitaClient creates a new instance of RitaClient with the provided configuration.
 //
+// New, with functional options, is the preferred way of creating a client; NewRitaClient
+// is kept for existing users.
+//
 // Parameters:
 //   - config: A pointer to a RitaConfig struct containing the configuration for the client.
 //
@@ -42,13 +89,101 @@ func NewRitaClient(config *RitaConfig) *RitaClient {
 	urlEventSend := "/v1/event/$"
 	urlEventSub := "/v1/event/$"
 	urlGetCursor := "/v1/event/$/last"
+	urlEventDel := "/v1/event/$"
+	urlEventWS := "/v1/event/$/ws"
+	urlPing := "/v1/ping"
+	if pingPath := strings.TrimSpace(config.PingPath); pingPath != "" {
+		urlPing = "/" + strings.TrimLeft(pingPath, "/")
+	}
+
+	httpClient := &http.Client{}
+	if config.HTTPClient != nil {
+		clientCopy := *config.HTTPClient
+		httpClient = &clientCopy
+	}
+	if config.Timeout > 0 {
+		httpClient.Timeout = config.Timeout
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+
+	var configErr error
+	if proxyUrl := strings.TrimSpace(config.ProxyURL); proxyUrl != "" {
+		proxy, err := parseProxyUrl(proxyUrl)
+		if err != nil {
+			configErr = err
+		} else if transport, ok := withProxy(httpClient.Transport, proxy); ok {
+			httpClient.Transport = transport
+		} else {
+			logger.Warn("rita proxy url ignored: the transport of the http client is not an *http.Transport")
+		}
+	}
+
+	sseClient := &http.Client{
+		Transport:     sseTransport(httpClient.Transport),
+		CheckRedirect: httpClient.CheckRedirect,
+		Jar:           httpClient.Jar,
+	}
+
+	authHeader := strings.TrimSpace(config.AuthHeader)
+	if authHeader == "" {
+		authHeader = "Authorization"
+	}
+
+	retryBackoff := config.RetryBackoff
+	if retryBackoff <= 0 {
+		retryBackoff = defaultRetryBackoff
+	}
+
+	var sem chan struct{}
+	if config.MaxConcurrentSends > 0 {
+		sem = make(chan struct{}, config.MaxConcurrentSends)
+	}
+
+	maxFrameSize := config.MaxFrameSize
+	if maxFrameSize == 0 {
+		maxFrameSize = defaultMaxFrameSize
+	} else if maxFrameSize < 0 {
+		maxFrameSize = 0
+	}
+
+	var reconnect *reconnectPolicy
+	if config.Reconnect {
+		reconnect = newReconnectPolicy(config.ReconnectMinDelay, config.ReconnectMaxDelay)
+		reconnect.dedupe = config.ReconnectDedupe
+	}
 
 	return &RitaClient{
 		urlEventSend: urlEventSend,
 		urlEventSub:  urlEventSub,
 		urlGetCursor: urlGetCursor,
+		urlEventDel:  urlEventDel,
+		urlEventWS:   urlEventWS,
+		urlPing:      urlPing,
 		server:       strings.TrimSpace(config.Url),
 		apikey:       strings.TrimSpace(config.ApiKey),
+		authHeader:   authHeader,
+		authScheme:   strings.TrimSpace(config.AuthScheme),
+		httpClient:   httpClient,
+		sseClient:    sseClient,
+		logger:       logger,
+		maxRetries:   config.MaxRetries,
+		retryBackoff: retryBackoff,
+
+		disableCompression:      config.DisableCompression,
+		subscriptionCompression: config.SubscriptionCompression,
+		allowNullPayload:        config.AllowNullPayload,
+		maxFrameSize:            maxFrameSize,
+		reconnect:               reconnect,
+		decorate:                config.RequestDecorator,
+		responseHook:            config.ResponseHook,
+		sem:                     sem,
+		latestEventsParam:       strings.TrimSpace(config.LatestEventsParam),
+		configErr:               configErr,
+		config:                  *config,
 		//LogInConsole: config.LogInConsole,
 	}
 }
@@ -78,6 +213,85 @@ func NewRitaClient(config *RitaConfig) *RitaClient {
 	...
 */
 func (c *RitaClient) GetCursor(channel string) (string, error) {
+	return c.getCursor(context.Background(), channel)
+}
+
+// GetCursorContext returns the last event id of the channel like GetCursor, using ctx for the request.
+func (c *RitaClient) GetCursorContext(ctx context.Context, channel string) (string, error) {
+	return c.getCursor(ctx, channel)
+}
+
+// GetCursors returns the last event id of each of the channels passed by parameter,
+// fetching them concurrently within MaxConcurrentSends.
+//
+// A failing channel doesn't fail the batch: the returned map holds the cursors that
+// were fetched and the error, a ChannelErrors, the error of each failed channel.
+//
+// Parameters:
+//   - channels: The names of the channels for which to retrieve the cursors.
+//
+// Returns:
+//   - map[string]string: The cursor of each channel fetched, keyed by the names as passed.
+//   - error: A ChannelErrors when the request of any channel failed.
+func (c *RitaClient) GetCursors(channels []string) (map[string]string, error) {
+	return c.GetCursorsContext(context.Background(), channels)
+}
+
+// GetCursorsContext is like GetCursors but the requests not yet done fail when ctx is done.
+func (c *RitaClient) GetCursorsContext(ctx context.Context, channels []string) (map[string]string, error) {
+	cursors := make(map[string]string, len(channels))
+	failed := ChannelErrors{}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+
+	// without MaxConcurrentSends the batch still doesn't open a connection per channel at once
+	workers := make(chan struct{}, defaultCursorsConcurrency)
+
+	seen := make(map[string]bool, len(channels))
+
+	for _, channel := range channels {
+		if seen[channel] {
+			continue
+		}
+		seen[channel] = true
+
+		wg.Add(1)
+		go func(channel string) {
+			defer wg.Done()
+
+			var cursor string
+			var err error
+
+			select {
+			case workers <- struct{}{}:
+				cursor, err = c.getCursor(ctx, channel)
+				<-workers
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+
+			if err != nil {
+				failed[channel] = err
+				return
+			}
+			cursors[channel] = cursor
+		}(channel)
+	}
+
+	wg.Wait()
+
+	if len(failed) > 0 {
+		return cursors, failed
+	}
+
+	return cursors, nil
+}
+
+func (c *RitaClient) getCursor(ctx context.Context, channel string) (string, error) {
 	channel, err := c.ensureCan(channel)
 	if err != nil {
 		return "", err
@@ -87,42 +301,25 @@ func (c *RitaClient) GetCursor(channel string) (string, error) {
 		return "", err
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
-	if err != nil {
-		return "", err
-	}
-
-	req.Header.Set("Authorization", c.apikey)
-	req.Header.Set("Content-Type", "application/json")
-
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	status, body, err := c.do(ctx, "GET", channel, url, nil, http.Header{
+		"Content-Type": {"application/json"},
+	})
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	switch resp.StatusCode {
+	switch status {
 	case 200:
 		var cursorResponse getCursorResponse
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return "", err
-		}
-
 		err = json.Unmarshal(body, &cursorResponse)
 		if err != nil {
 			return "", err
 		}
 
 		return cursorResponse.EventId, nil
-	case 401:
-		return "", NotAuthorized
-	case 403, 404:
-		return "", Forbidden
 	default:
-		return "", UnknownError
+		return "", statusError(status)
 	}
 }
 
@@ -131,6 +328,7 @@ func (c *RitaClient) GetCursor(channel string) (string, error) {
 // Parameters:
 //   - channel: The name of the channel to which the event will be sent.
 //   - data: The data to be sent as the event. This May be any type that can be marshaled into JSON.
+//     Data marshaling to null, such as nil, fails with EmptyPayload unless AllowNullPayload is set.
 //
 // Returns:
 //   - string: The event ID of the sent event.
@@ -150,6 +348,106 @@ func (c *RitaClient) GetCursor(channel string) (string, error) {
 //	fmt.Println(eventID)
 //	...
 func (c *RitaClient) SendEvent(channel string, data interface{}) (string, error) {
+	return c.sendEvent(context.Background(), channel, data, http.Header{})
+}
+
+// SendEventContext sends an event like SendEvent, using ctx for the request.
+//
+// When MaxConcurrentSends is configured and reached, the call waits for a slot
+// until ctx is done, returning the error of ctx.
+func (c *RitaClient) SendEventContext(ctx context.Context, channel string, data interface{}) (string, error) {
+	return c.sendEvent(ctx, channel, data, http.Header{})
+}
+
+// SendEventWithKey sends an event like SendEvent, attaching key as the Idempotency-Key header
+// so the server can discard a duplicate of an event it already received.
+//
+// The same key is sent on every retry of the request, so a retried send can't create a
+// duplicate event. When key is empty no header is sent and the call behaves like SendEvent.
+//
+// Parameters:
+//   - channel: The name of the channel to which the event will be sent.
+//   - data: The data to be sent as the event. This May be any type that can be marshaled into JSON.
+//   - key: The idempotency key of the event.
+//
+// Returns:
+//   - string: The event ID of the sent event.
+//   - error: An error if the request fails or the event cannot be sent.
+//
+// Example:
+//
+//	...
+//	eventID, err := client.SendEventWithKey("test", order, "order-"+order.Id)
+//	...
+func (c *RitaClient) SendEventWithKey(channel string, data interface{}, key string) (string, error) {
+	header := http.Header{}
+	if key = strings.TrimSpace(key); key != "" {
+		header.Set("Idempotency-Key", key)
+	}
+
+	return c.sendEvent(context.Background(), channel, data, header)
+}
+
+// SendEventAt sends an event like SendEvent, asking the server to record createdAt as its
+// time instead of the time it is received, e.g. to backfill historical data.
+//
+// The time is sent in UTC as RFC 3339 with nanoseconds in the X-Created-At header. When
+// createdAt is zero the call behaves like SendEvent.
+//
+// Parameters:
+//   - channel: The name of the channel to which the event will be sent.
+//   - data: The data to be sent as the event. This May be any type that can be marshaled into JSON.
+//   - createdAt: The time of the event.
+//
+// Returns:
+//   - string: The event ID of the sent event.
+//   - error: CreatedAtNotAccepted when the server rejects the time: a 400 or 422 response whose
+//     JSON body has "field": "createdAt" or "code": "created_at_not_accepted". Other rejections
+//     are reported like SendEvent, and so is any error if the request fails.
+//
+// Example:
+//
+//	...
+//	eventID, err := client.SendEventAt("orders", order, order.PlacedAt)
+//	...
+func (c *RitaClient) SendEventAt(channel string, data interface{}, createdAt time.Time) (string, error) {
+	header := http.Header{}
+	if !createdAt.IsZero() {
+		header.Set(createdAtHeader, createdAt.UTC().Format(time.RFC3339Nano))
+	}
+
+	return c.sendEvent(context.Background(), channel, data, header)
+}
+
+// ValidateEvent runs the checks SendEvent does before sending an event, without any
+// network call: the configuration and the channel name are validated and the data
+// is marshaled. It lets payload construction be tested cheaply, e.g. in CI.
+//
+// Parameters:
+//   - channel: The name of the channel to which the event would be sent.
+//   - data: The data of the event.
+//
+// Returns:
+//   - error: ChannelNotValid, JsonNotValid, EmptyPayload or a configuration error when
+//     SendEvent would fail before sending; nil otherwise.
+//
+// Example:
+//
+//	...
+//	if err := client.ValidateEvent("orders", order); err != nil {
+//		t.Fatal(err)
+//	}
+//	...
+func (c *RitaClient) ValidateEvent(channel string, data interface{}) error {
+	if _, err := c.ensureCan(channel); err != nil {
+		return err
+	}
+
+	_, err := c.marshalData(data)
+	return err
+}
+
+func (c *RitaClient) sendEvent(ctx context.Context, channel string, data interface{}, header http.Header) (string, error) {
 	channel, err := c.ensureCan(channel)
 	if err != nil {
 		return "", err
@@ -160,47 +458,35 @@ func (c *RitaClient) SendEvent(channel string, data interface{}) (string, error)
 		return "", err
 	}
 
-	_bytes, err := json.Marshal(data)
-	if err != nil {
-		return "", JsonNotValid
-	}
-
-	req, err := http.NewRequest("POST", url, bytes.NewReader(_bytes))
+	_bytes, err := c.marshalData(data)
 	if err != nil {
 		return "", err
 	}
 
-	req.Header.Set("Authorization", c.apikey)
-	req.Header.Set("Content-Type", "application/json")
+	header.Set("Content-Type", "application/json")
 
-	client := &http.Client{}
-	resp, err := client.Do(req)
+	status, body, err := c.do(ctx, "POST", channel, url, _bytes, header)
 	if err != nil {
 		return "", err
 	}
-	defer resp.Body.Close()
 
-	switch resp.StatusCode {
+	switch status {
 	case 200:
 		var cursorResponse getCursorResponse
 
-		body, err := io.ReadAll(resp.Body)
-		if err != nil {
-			return "", err
-		}
-
 		err = json.Unmarshal(body, &cursorResponse)
 		if err != nil {
 			return "", err
 		}
 
 		return cursorResponse.EventId, nil
-	case 401:
-		return "", NotAuthorized
-	case 403, 404:
-		return "", Forbidden
+	case http.StatusBadRequest, http.StatusUnprocessableEntity:
+		if header.Get(createdAtHeader) != "" && rejectsCreatedAt(body) {
+			return "", CreatedAtNotAccepted
+		}
+		return "", statusError(status)
 	default:
-		return "", UnknownError
+		return "", statusError(status)
 	}
 }
 
@@ -254,11 +540,118 @@ Returns:
 	...
 */
 func (c *RitaClient) SubEventSince(channel string, eventId string) (chan *RitaEvent, error) {
+	return c.SubEventSinceContext(context.Background(), channel, eventId)
+}
+
+/*
+SubEventSinceContext is like SubEventSince but the subscription ends when ctx is cancelled.
+
+The goroutine reading the stream exits and the connection is closed once ctx is done,
+even if the returned channel is never received from. Without a context, as in SubEventSince,
+the goroutine only exits when the server ends the stream.
+
+Parameters:
+  - ctx: The context that controls the lifetime of the subscription.
+  - channel: The name of the channel from which to receive events.
+  - eventId: The ID of the event from which to start receiving events.
+
+Returns:
+  - chan *RitaEvent: A channel that will receive events from the specified channel.
+  - error: An error if the request fails or the channel cannot be accessed.
+
+# Example
+
+	...
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, _ := client.SubEventSinceContext(ctx, "test", ritago.LAST_EVENT)
+	for event := range events {
+		fmt.Println(event)
+	}
+	...
+*/
+func (c *RitaClient) SubEventSinceContext(ctx context.Context, channel string, eventId string) (chan *RitaEvent, error) {
+	sub, err := c.Subscribe(ctx, channel, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	return sub.events, nil
+}
+
+/*
+Subscribe opens a subscription to the specified channel starting from the specified event ID.
+
+Unlike SubEventSince, the returned Subscription also exposes the errors sent by the server
+(for example an `event: error` frame) and the errors found while reading the stream.
+
+The subscription ends when ctx is cancelled or Close is called, even if nobody is
+receiving from the events channel.
+
+Parameters:
+  - ctx: The context used for the request. Cancelling it ends the subscription.
+  - channel: The name of the channel from which to receive events.
+  - eventId: The ID of the event from which to start receiving events.
+  - opts: The options of the subscription, such as WithOnProgress.
+
+Returns:
+  - *Subscription: The subscription delivering events and errors.
+  - error: An error if the request fails or the channel cannot be accessed.
+
+# Example
+
+	...
+	client := ritago.NewRitaClient(ritaConfig)
+
+	sub, _ := client.Subscribe(ctx, "test", ritago.LAST_EVENT)
+	go func() {
+		for err := range sub.Errors() {
+			fmt.Println(err)
+		}
+	}()
+	for event := range sub.Events() {
+		fmt.Println(event)
+	}
+	...
+*/
+func (c *RitaClient) Subscribe(ctx context.Context, channel string, eventId string, opts ...SubscribeOption) (*Subscription, error) {
+	return c.subscribe(ctx, channel, eventId, c.openStream, opts)
+}
+
+// subscribe opens a subscription whose stream is opened, and reopened on reconnection, by open.
+func (c *RitaClient) subscribe(ctx context.Context, channel string, eventId string, open func(ctx context.Context, channel string, eventId string) (stream, error), opts []SubscribeOption) (*Subscription, error) {
 	channel, err := c.ensureCan(channel)
 	if err != nil {
 		return nil, err
 	}
 
+	sub := newSubscription(c.logger)
+	for _, opt := range opts {
+		opt(sub)
+	}
+	sub.setState(Connecting)
+
+	opened, err := open(ctx, channel, eventId)
+	if err != nil {
+		return nil, err
+	}
+
+	sub.stream = opened
+	sub.resumeId = eventId
+	sub.reconnect = c.reconnect
+	sub.open = func(ctx context.Context, eventId string) (stream, error) {
+		return open(ctx, channel, eventId)
+	}
+	sub.setState(Connected)
+
+	go sub.consume(ctx)
+
+	return sub, nil
+}
+
+// openStream sends the subscription request and returns the event stream of the response.
+func (c *RitaClient) openStream(ctx context.Context, channel string, eventId string) (stream, error) {
 	queryParams := map[string]string{
 		"eventId": "",
 		"sub":     "true",
@@ -273,75 +666,123 @@ func (c *RitaClient) SubEventSince(channel string, eventId string) (chan *RitaEv
 		return nil, err
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	c.logger.Debug("rita request", "method", "GET", "url", url)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
 	if err != nil {
 		return nil, err
 	}
 
-	req.Header.Set("Authorization", c.apikey)
+	c.setAuth(req)
 	req.Header.Set("Connection", "keep-alive")
 	req.Header.Set("Accept", "text/event-stream")
+	// compressed streams may be buffered until a whole block is available, see
+	// RitaConfig.SubscriptionCompression
+	if c.subscriptionCompression {
+		req.Header.Set("Accept-Encoding", "gzip")
+	} else {
+		req.Header.Set("Accept-Encoding", "identity")
+	}
 
-	client := &http.Client{}
-	transport := &http.Transport{}
-	transport.DisableCompression = true
-	client.Transport = transport
+	c.decorateRequest(ctx, req)
 
-	resp, err := client.Do(req)
+	start := time.Now()
+	resp, err := c.sseClient.Do(req)
 	if err != nil {
+		c.observe("GET", channel, 0, start)
 		return nil, err
 	}
+	c.observe("GET", channel, resp.StatusCode, start)
 
 	switch resp.StatusCode {
 	case 200:
-		ch := make(chan *RitaEvent)
-
-		reader := bufio.NewReader(resp.Body)
-		if reader == nil {
-			return nil, UnknownError
+		body := resp.Body
+		if strings.EqualFold(resp.Header.Get("Content-Encoding"), "gzip") {
+			body = &gzipBody{body: body}
 		}
 
-		go func() { // goroutine
-			for {
-				line, err := reader.ReadBytes('\n')
-				if err != nil {
-					fmt.Println(err)
-					resp.Body.Close()
-					close(ch)
-					break
-				}
+		return newSseStream(body, c.maxFrameSize), nil
+	default:
+		resp.Body.Close()
+		return nil, statusError(resp.StatusCode)
+	}
+}
 
-				strLine := strings.TrimSpace(string(line))
+/*
+SubEventTail returns a channel that will receive the last n events of the specified channel, oldest
+first, followed by the live events, like `tail -f -n`.
 
-				if strings.HasPrefix(strLine, "data:") {
-					eventData := strings.TrimPrefix(strLine, "data:")
-					eventData = strings.TrimSpace(eventData)
+The live stream starts from the last replayed event, so no event is missed or repeated at the
+boundary. When the channel has no events yet the live stream starts like SubEvent. The past
+events are read like GetLatestEvents, with the same cost and errors.
 
-					if eventData == "" || eventData == "ping" {
-						continue
-					}
+Parameters:
+  - channel: The name of the channel from which to receive events.
+  - n: The number of past events to replay.
 
-					var event RitaEvent
-					err := json.Unmarshal([]byte(eventData), &event)
+Returns:
+  - chan *RitaEvent: A channel that will receive the replayed and the live events.
+  - error: An error if the request fails or the channel cannot be accessed.
 
-					if err != nil {
-						fmt.Println(err)
-						continue
-					}
+# Example
 
-					ch <- &event
-				}
-			}
-		}()
+	...
+	events, _ := client.SubEventTail("test", 10)
+	for event := range events {
+		fmt.Println(event)
+	}
+	...
+*/
+func (c *RitaClient) SubEventTail(channel string, n int) (chan *RitaEvent, error) {
+	return c.SubEventTailContext(context.Background(), channel, n)
+}
 
-		return ch, nil
-	case 401:
-		return nil, NotAuthorized
-	case 403, 404:
-		return nil, Forbidden
-	default:
-		return nil, UnknownError
+// SubEventTailContext is like SubEventTail but the subscription ends when ctx is cancelled.
+func (c *RitaClient) SubEventTailContext(ctx context.Context, channel string, n int) (chan *RitaEvent, error) {
+	history, err := c.getLatestEvents(ctx, channel, n)
+	if err != nil {
+		return nil, err
 	}
+
+	since := ""
+	if len(history) > 0 {
+		since = history[len(history)-1].Id
+	}
+
+	sub, err := c.Subscribe(ctx, channel, since)
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan *RitaEvent)
+
+	go func() {
+		defer close(ch)
+		defer sub.Close()
+
+		for i := range history {
+			select {
+			case ch <- &history[i]:
+			case <-ctx.Done():
+				return
+			}
+		}
+
+		for event := range sub.events {
+			// servers treating eventId as inclusive send the last replayed event again
+			if since != "" && event.Id == since {
+				continue
+			}
+
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+
+	return ch, nil
 }
 
 /*
@@ -371,15 +812,51 @@ Returns:
   - error: An error if the request fails or the channel cannot be accessed.
 */
 func (c *RitaClient) GetEventsSince(channel string, eventId string) ([]RitaEvent, error) {
+	return c.getEvents(context.Background(), channel, eventId, nil)
+}
+
+// GetEventsSinceContext returns the events of the channel like GetEventsSince, using ctx for the request.
+func (c *RitaClient) GetEventsSinceContext(ctx context.Context, channel string, eventId string) ([]RitaEvent, error) {
+	return c.getEvents(ctx, channel, eventId, nil)
+}
+
+/*
+GetEventsSinceWith returns the events of the channel like GetEventsSince, adding extra to the query
+params of the request, e.g. to reach the filters of the server.
+
+The params used by the client itself, eventId and sub, are reserved: an entry of extra with either
+name is replaced by the value the client sends.
+
+Parameters:
+  - channel: The name of the channel from which to receive events.
+  - eventId: The ID of the event from which to start receiving events.
+  - extra: The query params added to the request; they are URL encoded by the client.
+
+Returns:
+  - []RitaEvent: A list of events from the specified channel.
+  - error: An error if the request fails or the channel cannot be accessed.
+
+Example:
+
+	events, err := client.GetEventsSinceWith("orders", "", map[string]string{"type": "created"})
+*/
+func (c *RitaClient) GetEventsSinceWith(channel string, eventId string, extra map[string]string) ([]RitaEvent, error) {
+	return c.getEvents(context.Background(), channel, eventId, extra)
+}
+
+// getEvents reads the events of channel after eventId, adding extra to the query params.
+func (c *RitaClient) getEvents(ctx context.Context, channel string, eventId string, extra map[string]string) ([]RitaEvent, error) {
 	channel, err := c.ensureCan(channel)
 	if err != nil {
 		return make([]RitaEvent, 0), err
 	}
 
-	queryParams := map[string]string{
-		"eventId": "",
-		"sub":     "false",
+	queryParams := map[string]string{}
+	for k, v := range extra {
+		queryParams[k] = v
 	}
+	queryParams["eventId"] = ""
+	queryParams["sub"] = "false"
 
 	if strings.TrimSpace(eventId) != "" {
 		queryParams["eventId"] = eventId
@@ -390,59 +867,273 @@ func (c *RitaClient) GetEventsSince(channel string, eventId string) ([]RitaEvent
 		return make([]RitaEvent, 0), err
 	}
 
-	req, err := http.NewRequest("GET", url, nil)
+	status, body, err := c.do(ctx, "GET", channel, url, nil, http.Header{
+		"Accept": {"application/json"},
+	})
 	if err != nil {
 		return make([]RitaEvent, 0), err
 	}
 
-	req.Header.Set("Authorization", c.apikey)
-	req.Header.Set("Accept", "application/json")
+	switch status {
+	case 200:
+		var r eventsResponse
 
-	client := &http.Client{}
+		err = json.Unmarshal(body, &r)
+		if err != nil {
+			return make([]RitaEvent, 0), err
+		}
 
-	resp, err := client.Do(req)
-	if err != nil {
-		return make([]RitaEvent, 0), err
+		return r.Events, nil
+	default:
+		return nil, statusError(status)
 	}
-	defer resp.Body.Close()
+}
 
-	switch resp.StatusCode {
-	case 200:
-		var r eventsResponse
+/*
+GetLatestEvents returns the last n events of the specified channel, oldest first.
+
+When LatestEventsParam is configured the events are requested from the server in a single
+request. Otherwise the tail of the channel is read with GetCursor and the channel is then read
+a page at a time up to that event, keeping only the last n events in memory: the call costs as
+many requests as pages the channel has, events sent while it runs are not included, and it fails
+with TailNotFound when the reading ends without reaching the tail, e.g. because the channel was
+cleared meanwhile. When the channel has fewer than n events all of them are returned.
+
+Parameters:
+  - channel: The name of the channel from which to read events.
+  - n: The number of events to return.
 
-		body, err := io.ReadAll(resp.Body)
+Returns:
+  - []RitaEvent: The last n events of the channel, oldest first.
+  - error: An error if the request fails or the channel cannot be accessed.
+*/
+func (c *RitaClient) GetLatestEvents(channel string, n int) ([]RitaEvent, error) {
+	return c.getLatestEvents(context.Background(), channel, n)
+}
+
+func (c *RitaClient) getLatestEvents(ctx context.Context, channel string, n int) ([]RitaEvent, error) {
+	if n <= 0 {
+		return make([]RitaEvent, 0), nil
+	}
 
+	if c.latestEventsParam != "" {
+		events, err := c.getEvents(ctx, channel, "", map[string]string{c.latestEventsParam: strconv.Itoa(n)})
 		if err != nil {
 			return make([]RitaEvent, 0), err
 		}
 
-		err = json.Unmarshal(body, &r)
-		if err != nil {
-			return make([]RitaEvent, 0), err
+		if len(events) > n {
+			events = events[len(events)-n:]
 		}
+		return events, nil
+	}
+
+	tail, err := c.getCursor(ctx, channel)
+	if err != nil {
+		return make([]RitaEvent, 0), err
+	}
 
-		//fmt.Println(r.Events)
+	if tail == "" {
+		return make([]RitaEvent, 0), nil
+	}
 
-		return r.Events, nil
-	case 401:
-		return nil, NotAuthorized
-	case 403, 404:
-		return nil, Forbidden
+	// the ring grows with the events read until it holds n of them, so a large n
+	// doesn't allocate more than the channel holds
+	var ring []RitaEvent
+	count := 0
+	reached := false
+
+	err = c.eachEvent(ctx, channel, "", func(event *RitaEvent) bool {
+		if len(ring) < n {
+			ring = append(ring, *event)
+		} else {
+			ring[count%n] = *event
+		}
+		count++
+		reached = event.Id == tail
+		return !reached
+	})
+	if err != nil {
+		return make([]RitaEvent, 0), err
+	}
+
+	if !reached {
+		return make([]RitaEvent, 0), TailNotFound
+	}
+
+	if count <= n {
+		return ring, nil
+	}
+
+	start := count % n
+	return append(ring[start:], ring[:start]...), nil
+}
+
+/*
+TrimChannel deletes the events of the specified channel older than the specified event ID.
+The event with that ID is kept.
+
+The operation is idempotent: trimming again with the same ID, or with an ID older than every
+remaining event, deletes nothing and succeeds. A channel the server answers 404 for is
+reported as NotFound.
+
+Parameters:
+  - channel: The name of the channel to trim.
+  - beforeId: The ID of the oldest event to keep.
+
+Returns:
+  - error: An error if the request fails or the channel cannot be accessed.
+*/
+func (c *RitaClient) TrimChannel(channel string, beforeId string) error {
+	beforeId = strings.TrimSpace(beforeId)
+	if beforeId == "" {
+		return EventIdNotValid
+	}
+
+	return c.deleteEvents(context.Background(), channel, map[string]string{"before": beforeId})
+}
+
+/*
+ClearChannel deletes every event of the specified channel.
+
+The operation is idempotent: clearing an empty channel succeeds. A channel the server
+answers 404 for is reported as NotFound.
+
+Parameters:
+  - channel: The name of the channel to clear.
+
+Returns:
+  - error: An error if the request fails or the channel cannot be accessed.
+*/
+func (c *RitaClient) ClearChannel(channel string) error {
+	return c.deleteEvents(context.Background(), channel, nil)
+}
+
+func (c *RitaClient) deleteEvents(ctx context.Context, channel string, queryParams map[string]string) error {
+	channel, err := c.ensureCan(channel)
+	if err != nil {
+		return err
+	}
+
+	var params *map[string]string
+	if queryParams != nil {
+		params = &queryParams
+	}
+
+	url, err := c.createUrl(channel, c.urlEventDel, params)
+	if err != nil {
+		return err
+	}
+
+	status, _, err := c.do(ctx, "DELETE", channel, url, nil, http.Header{})
+	if err != nil {
+		return err
+	}
+
+	switch status {
+	case 200, 202, 204:
+		return nil
 	default:
-		return nil, UnknownError
+		return statusError(status)
 	}
 }
 
-func (c *RitaClient) ensureCan(channel string) (string, error) {
-	channel = strings.TrimSpace(channel)
-	channel = strings.ToLower(channel)
+// Kinds of url accepted by BuildURL.
+const (
+	UrlKindSend   = "send"
+	UrlKindSub    = "sub"
+	UrlKindCursor = "cursor"
+	UrlKindDelete = "delete"
+	UrlKindWS     = "ws"
+)
 
-	if c.server == "" {
-		return "", ServerNotConfig
+/*
+BuildURL returns the url the client requests for the specified channel, endpoint kind and
+query params, as built by the other methods. It is meant for debugging misconfigured server
+urls and channel names; the urls are also logged at debug level before each request.
+
+Parameters:
+  - channel: The name of the channel.
+  - kind: The endpoint, one of UrlKindSend, UrlKindSub, UrlKindCursor, UrlKindDelete or UrlKindWS.
+  - params: The query params of the url. May be nil.
+
+Returns:
+  - string: The url of the request.
+  - error: An error if the channel, the kind or the server url are not valid.
+
+# Example
+
+	...
+	url, _ := client.BuildURL("test", ritago.UrlKindSub, map[string]string{"sub": "true"})
+	fmt.Println(url)
+	...
+*/
+func (c *RitaClient) BuildURL(channel, kind string, params map[string]string) (string, error) {
+	channel, err := c.ensureCan(channel)
+	if err != nil {
+		return "", err
 	}
 
-	if c.apikey == "" {
-		return "", ApikeyNotConfig
+	var _url string
+	switch kind {
+	case UrlKindSend:
+		_url = c.urlEventSend
+	case UrlKindSub:
+		_url = c.urlEventSub
+	case UrlKindCursor:
+		_url = c.urlGetCursor
+	case UrlKindDelete:
+		_url = c.urlEventDel
+	case UrlKindWS:
+		_url = c.urlEventWS
+	default:
+		return "", fmt.Errorf("unknown url kind %q", kind)
+	}
+
+	if params == nil {
+		return c.createUrl(channel, _url, nil)
+	}
+
+	return c.createUrl(channel, _url, &params)
+}
+
+// marshalData encodes the data of an event, rejecting data that encodes to null
+// unless the configuration allows it. Empty maps and slices are valid data.
+func (c *RitaClient) marshalData(data interface{}) ([]byte, error) {
+	_bytes, err := json.Marshal(data)
+	if err != nil {
+		return nil, JsonNotValid
+	}
+
+	if !c.allowNullPayload && string(_bytes) == "null" {
+		return nil, EmptyPayload
+	}
+
+	return _bytes, nil
+}
+
+// decorateRequest calls the request decorator of the configuration, if any.
+func (c *RitaClient) decorateRequest(ctx context.Context, req *http.Request) {
+	if c.decorate != nil {
+		c.decorate(ctx, req)
+	}
+}
+
+// setAuth adds the api key to req with the configured header and scheme.
+func (c *RitaClient) setAuth(req *http.Request) {
+	if c.authScheme == "" {
+		req.Header.Set(c.authHeader, c.apikey)
+		return
+	}
+
+	req.Header.Set(c.authHeader, c.authScheme+" "+c.apikey)
+}
+
+func (c *RitaClient) ensureCan(channel string) (string, error) {
+	channel = normalizeChannel(channel)
+
+	if err := c.ensureConfig(); err != nil {
+		return "", err
 	}
 
 	if channel == "" {
@@ -452,6 +1143,28 @@ func (c *RitaClient) ensureCan(channel string) (string, error) {
 	return channel, nil
 }
 
+// normalizeChannel returns the form of channel sent to the server.
+func normalizeChannel(channel string) string {
+	return strings.ToLower(strings.TrimSpace(channel))
+}
+
+// ensureConfig returns the error of the configuration that makes every request fail, if any.
+func (c *RitaClient) ensureConfig() error {
+	if c.configErr != nil {
+		return c.configErr
+	}
+
+	if c.server == "" {
+		return ServerNotConfig
+	}
+
+	if c.apikey == "" {
+		return ApikeyNotConfig
+	}
+
+	return nil
+}
+
 func (c *RitaClient) createUrl(channel, _url string, queryParams *map[string]string) (string, error) {
 	u, err := url.Parse(c.server)
 	if err != nil {
@@ -18,12 +18,16 @@ var ritaConfig *ritago.RitaConfig
 var client *ritago.RitaClient
 
 func init() {
-	file, _ := os.Open("env.test.json")
+	file, err := os.Open("env.test.json")
+	if err != nil {
+		// without a server to talk to, the integration tests are skipped
+		return
+	}
 	defer file.Close()
 	decoder := json.NewDecoder(file)
 
 	env := env{}
-	err := decoder.Decode(&env)
+	err = decoder.Decode(&env)
 	if err != nil {
 		panic(err)
 	}
@@ -51,6 +55,10 @@ func TestGetEvents(t *testing.T) {
 */
 
 func TestSubEvent(t *testing.T) {
+	if client == nil {
+		t.Skip("env.test.json not found")
+	}
+
 	channel := "test"
 
 	fmt.Println("Start")
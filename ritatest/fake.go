@@ -0,0 +1,529 @@
+// Package ritatest provides an in-memory implementation of ritago.Client for
+// testing code that uses the Rita client without a live server.
+package ritatest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+
+	ritago "github.com/Pyxis-GMS/rita-go"
+)
+
+// FakeClient is an in-memory ritago.Client. Sent events are stored per channel
+// with increasing ids and delivered to the subscriptions of the channel.
+//
+// Data is round-tripped through JSON, so consumers read it back as the real
+// client returns it, e.g. map[string]interface{} for a struct.
+type FakeClient struct {
+	// Err, when set, is returned by every method instead of running it.
+	Err error
+	// Now returns the CreatedAt of the sent events. Defaults to time.Now.
+	Now func() time.Time
+	// AllowNullPayload accepts data marshaling to null, as RitaConfig.AllowNullPayload.
+	AllowNullPayload bool
+
+	mu     sync.Mutex
+	seq    int64
+	events map[string][]ritago.RitaEvent
+	keys   map[string]string
+	subs   map[string][]*fakeSubscription
+	done   chan struct{}
+	closed bool
+}
+
+var _ ritago.Client = (*FakeClient)(nil)
+
+type fakeSubscription struct {
+	notify chan struct{}
+}
+
+// NewFakeClient returns an empty FakeClient.
+func NewFakeClient() *FakeClient {
+	return &FakeClient{
+		events: map[string][]ritago.RitaEvent{},
+		keys:   map[string]string{},
+		subs:   map[string][]*fakeSubscription{},
+		done:   make(chan struct{}),
+	}
+}
+
+// Sent returns a copy of the events stored in channel.
+func (f *FakeClient) Sent(channel string) []ritago.RitaEvent {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	return append([]ritago.RitaEvent(nil), f.events[normalize(channel)]...)
+}
+
+// Close ends every subscription, closing their channels.
+func (f *FakeClient) Close() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if !f.closed {
+		f.closed = true
+		close(f.done)
+	}
+}
+
+func normalize(channel string) string {
+	return strings.ToLower(strings.TrimSpace(channel))
+}
+
+func (f *FakeClient) ensureCan(channel string) (string, error) {
+	if f.Err != nil {
+		return "", f.Err
+	}
+
+	channel = normalize(channel)
+	if channel == "" {
+		return "", ritago.ChannelNotValid
+	}
+
+	return channel, nil
+}
+
+// Ping returns Err, failing with the error of ctx when it is done.
+func (f *FakeClient) Ping(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	return f.Err
+}
+
+// WaitForReady returns like Ping, without waiting: the fake is always up unless Err is set.
+func (f *FakeClient) WaitForReady(ctx context.Context, interval time.Duration) error {
+	return f.Ping(ctx)
+}
+
+func (f *FakeClient) GetCursor(channel string) (string, error) {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return "", err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	events := f.events[channel]
+	if len(events) == 0 {
+		return "", nil
+	}
+
+	return events[len(events)-1].Id, nil
+}
+
+// GetCursorContext returns the cursor like GetCursor, failing with the error of ctx when it is done.
+func (f *FakeClient) GetCursorContext(ctx context.Context, channel string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return f.GetCursor(channel)
+}
+
+// GetCursors returns the cursor of each channel, collecting the failures in a ritago.ChannelErrors.
+func (f *FakeClient) GetCursors(channels []string) (map[string]string, error) {
+	return f.GetCursorsContext(context.Background(), channels)
+}
+
+// GetCursorsContext returns the cursors like GetCursors, failing with the error of ctx when it is done.
+func (f *FakeClient) GetCursorsContext(ctx context.Context, channels []string) (map[string]string, error) {
+	if err := ctx.Err(); err != nil {
+		return map[string]string{}, err
+	}
+
+	cursors := map[string]string{}
+	failed := ritago.ChannelErrors{}
+
+	for _, channel := range channels {
+		cursor, err := f.GetCursor(channel)
+		if err != nil {
+			failed[channel] = err
+			continue
+		}
+		cursors[channel] = cursor
+	}
+
+	if len(failed) > 0 {
+		return cursors, failed
+	}
+
+	return cursors, nil
+}
+
+func (f *FakeClient) SendEvent(channel string, data interface{}) (string, error) {
+	return f.SendEventWithKey(channel, data, "")
+}
+
+// SendEventContext stores the event like SendEvent, failing with the error of ctx when it is done.
+func (f *FakeClient) SendEventContext(ctx context.Context, channel string, data interface{}) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+
+	return f.SendEvent(channel, data)
+}
+
+// SendEventWithKey stores the event unless an event was already sent to the channel with
+// the same non empty key, in which case the id of that event is returned.
+func (f *FakeClient) SendEventWithKey(channel string, data interface{}, key string) (string, error) {
+	return f.send(channel, data, key, time.Time{})
+}
+
+// SendEventAt stores the event like SendEvent, with createdAt as its CreatedAt.
+func (f *FakeClient) SendEventAt(channel string, data interface{}, createdAt time.Time) (string, error) {
+	return f.send(channel, data, "", createdAt)
+}
+
+// send stores the event, created now when createdAt is zero.
+func (f *FakeClient) send(channel string, data interface{}, key string, createdAt time.Time) (string, error) {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return "", err
+	}
+
+	raw, err := f.marshal(data)
+	if err != nil {
+		return "", err
+	}
+
+	var decoded interface{}
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		return "", ritago.JsonNotValid
+	}
+
+	if createdAt.IsZero() {
+		createdAt = time.Now()
+		if f.Now != nil {
+			createdAt = f.Now()
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	key = strings.TrimSpace(key)
+	if id, ok := f.keys[channel+"\x00"+key]; ok && key != "" {
+		return id, nil
+	}
+
+	f.seq++
+	id := fmt.Sprintf("%d-0", f.seq)
+
+	f.events[channel] = append(f.events[channel], ritago.RitaEvent{
+		Id:        id,
+		CreatedAt: createdAt.UTC(),
+		Data:      decoded,
+	})
+
+	if key != "" {
+		f.keys[channel+"\x00"+key] = id
+	}
+
+	for _, sub := range f.subs[channel] {
+		select {
+		case sub.notify <- struct{}{}:
+		default:
+		}
+	}
+
+	return id, nil
+}
+
+// ValidateEvent checks the channel and the data like SendEvent, without storing anything.
+func (f *FakeClient) ValidateEvent(channel string, data interface{}) error {
+	if _, err := f.ensureCan(channel); err != nil {
+		return err
+	}
+
+	_, err := f.marshal(data)
+	return err
+}
+
+func (f *FakeClient) marshal(data interface{}) ([]byte, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, ritago.JsonNotValid
+	}
+
+	if !f.AllowNullPayload && string(raw) == "null" {
+		return nil, ritago.EmptyPayload
+	}
+
+	return raw, nil
+}
+
+func (f *FakeClient) SubEvent(channel string) (chan *ritago.RitaEvent, error) {
+	return f.SubEventSince(channel, "")
+}
+
+func (f *FakeClient) SubEventSince(channel string, eventId string) (chan *ritago.RitaEvent, error) {
+	return f.SubEventSinceContext(context.Background(), channel, eventId)
+}
+
+// SubEventSinceContext delivers the stored events after eventId, then the events sent
+// afterwards, until ctx is done or the client is closed. An empty eventId or
+// ritago.LAST_EVENT delivers only the events sent afterwards.
+func (f *FakeClient) SubEventSinceContext(ctx context.Context, channel string, eventId string) (chan *ritago.RitaEvent, error) {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	sub := &fakeSubscription{notify: make(chan struct{}, 1)}
+
+	f.mu.Lock()
+	after := f.seqAfter(strings.TrimSpace(eventId))
+	f.subs[channel] = append(f.subs[channel], sub)
+	f.mu.Unlock()
+
+	ch := make(chan *ritago.RitaEvent)
+
+	go func() {
+		defer func() {
+			f.removeSubscription(channel, sub)
+			close(ch)
+		}()
+
+		for {
+			f.mu.Lock()
+			var event *ritago.RitaEvent
+			for _, stored := range f.events[channel] {
+				if seq := seqOf(stored.Id); seq > after {
+					copied := stored
+					event = &copied
+					after = seq
+					break
+				}
+			}
+			f.mu.Unlock()
+
+			if event == nil {
+				select {
+				case <-sub.notify:
+					continue
+				case <-ctx.Done():
+					return
+				case <-f.done:
+					return
+				}
+			}
+
+			select {
+			case ch <- event:
+			case <-ctx.Done():
+				return
+			case <-f.done:
+				return
+			}
+		}
+	}()
+
+	return ch, nil
+}
+
+// SubEventWS delivers the events like SubEvent; the fake has a single transport.
+func (f *FakeClient) SubEventWS(channel string) (chan *ritago.RitaEvent, error) {
+	return f.SubEvent(channel)
+}
+
+// SubEventTail delivers the last n stored events, then the events sent afterwards.
+func (f *FakeClient) SubEventTail(channel string, n int) (chan *ritago.RitaEvent, error) {
+	return f.SubEventTailContext(context.Background(), channel, n)
+}
+
+// SubEventTailContext delivers the events like SubEventTail, until ctx is done or the client is closed.
+func (f *FakeClient) SubEventTailContext(ctx context.Context, channel string, n int) (chan *ritago.RitaEvent, error) {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return nil, err
+	}
+
+	f.mu.Lock()
+	events := f.events[channel]
+	since := ritago.LAST_EVENT
+	if n > 0 && len(events) > 0 {
+		start := len(events) - n
+		if start < 0 {
+			start = 0
+		}
+		since = fmt.Sprintf("%d-0", seqOf(events[start].Id)-1)
+	}
+	f.mu.Unlock()
+
+	return f.SubEventSinceContext(ctx, channel, since)
+}
+
+// Subscribe delivers the events like SubEventSinceContext on a ritago.Subscription. The
+// fake neither disconnects nor reconnects: the subscription stays Connected until ctx is
+// done, it is closed or the client is closed, and nothing is delivered on Errors().
+func (f *FakeClient) Subscribe(ctx context.Context, channel string, eventId string, opts ...ritago.SubscribeOption) (*ritago.Subscription, error) {
+	sourceCtx, cancel := context.WithCancel(ctx)
+
+	events, err := f.SubEventSinceContext(sourceCtx, channel, eventId)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	return ritago.NewSubscription(ctx, &fakeSource{events: events, cancel: cancel}, opts...), nil
+}
+
+// SubscribeWS opens a subscription like Subscribe; the fake has a single transport.
+func (f *FakeClient) SubscribeWS(ctx context.Context, channel string, eventId string, opts ...ritago.SubscribeOption) (*ritago.Subscription, error) {
+	return f.Subscribe(ctx, channel, eventId, opts...)
+}
+
+// fakeSource feeds a ritago.Subscription with the events of a fake subscription.
+type fakeSource struct {
+	events chan *ritago.RitaEvent
+	cancel context.CancelFunc
+}
+
+func (s *fakeSource) Next() (*ritago.RitaEvent, error) {
+	event, ok := <-s.events
+	if !ok {
+		return nil, io.EOF
+	}
+
+	return event, nil
+}
+
+func (s *fakeSource) Close() error {
+	s.cancel()
+	return nil
+}
+
+// seqAfter returns the sequence number after which events are delivered for
+// eventId. f.mu must be held.
+func (f *FakeClient) seqAfter(eventId string) int64 {
+	if eventId == "" || eventId == ritago.LAST_EVENT {
+		return f.seq
+	}
+
+	return seqOf(eventId)
+}
+
+// seqOf returns the sequence number of an id generated by the fake, 0 for
+// any other id.
+func seqOf(id string) int64 {
+	var seq int64
+	fmt.Sscanf(id, "%d-0", &seq)
+
+	return seq
+}
+
+func (f *FakeClient) removeSubscription(channel string, sub *fakeSubscription) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	subs := f.subs[channel]
+	for i := range subs {
+		if subs[i] == sub {
+			f.subs[channel] = append(subs[:i], subs[i+1:]...)
+			return
+		}
+	}
+}
+
+func (f *FakeClient) GetEvents(channel string) ([]ritago.RitaEvent, error) {
+	return f.GetEventsSince(channel, "")
+}
+
+// GetEventsSince returns the stored events after eventId, or every stored event when
+// eventId is empty.
+func (f *FakeClient) GetEventsSince(channel string, eventId string) ([]ritago.RitaEvent, error) {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return make([]ritago.RitaEvent, 0), err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	after := seqOf(strings.TrimSpace(eventId))
+
+	events := make([]ritago.RitaEvent, 0)
+	for _, event := range f.events[channel] {
+		if seqOf(event.Id) > after {
+			events = append(events, event)
+		}
+	}
+
+	return events, nil
+}
+
+// GetEventsSinceWith returns the events like GetEventsSince. The fake has no filters: extra is ignored.
+func (f *FakeClient) GetEventsSinceWith(channel string, eventId string, extra map[string]string) ([]ritago.RitaEvent, error) {
+	return f.GetEventsSince(channel, eventId)
+}
+
+// GetEventsSinceContext returns the events like GetEventsSince, failing with the error of ctx when it is done.
+func (f *FakeClient) GetEventsSinceContext(ctx context.Context, channel string, eventId string) ([]ritago.RitaEvent, error) {
+	if err := ctx.Err(); err != nil {
+		return make([]ritago.RitaEvent, 0), err
+	}
+
+	return f.GetEventsSince(channel, eventId)
+}
+
+func (f *FakeClient) GetLatestEvents(channel string, n int) ([]ritago.RitaEvent, error) {
+	events, err := f.GetEventsSince(channel, "")
+	if err != nil || n <= 0 {
+		return make([]ritago.RitaEvent, 0), err
+	}
+
+	if len(events) > n {
+		events = events[len(events)-n:]
+	}
+
+	return events, nil
+}
+
+func (f *FakeClient) TrimChannel(channel string, beforeId string) error {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return err
+	}
+
+	beforeId = strings.TrimSpace(beforeId)
+	if beforeId == "" {
+		return ritago.EventIdNotValid
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	before := seqOf(beforeId)
+
+	kept := f.events[channel][:0]
+	for _, event := range f.events[channel] {
+		if seqOf(event.Id) >= before {
+			kept = append(kept, event)
+		}
+	}
+	f.events[channel] = kept
+
+	return nil
+}
+
+func (f *FakeClient) ClearChannel(channel string) error {
+	channel, err := f.ensureCan(channel)
+	if err != nil {
+		return err
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.events, channel)
+
+	return nil
+}
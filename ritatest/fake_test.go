@@ -0,0 +1,163 @@
+package ritatest_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	ritago "github.com/Pyxis-GMS/rita-go"
+	"github.com/Pyxis-GMS/rita-go/ritatest"
+)
+
+// publish is the kind of consumer code the fake is meant to test.
+func publish(client ritago.Client, orders []string) error {
+	for _, order := range orders {
+		if _, err := client.SendEventWithKey("orders", map[string]string{"order": order}, order); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestFakeClientSendAndRead(t *testing.T) {
+	fake := ritatest.NewFakeClient()
+
+	if err := publish(fake, []string{"a", "b", "a"}); err != nil {
+		t.Fatal(err)
+	}
+
+	events, err := fake.GetEvents("Orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(events) != 2 || events[0].Id != "1-0" || events[1].Id != "2-0" {
+		t.Fatalf("expected the duplicate key to be discarded, got %+v", events)
+	}
+
+	if data, ok := events[1].Data.(map[string]interface{}); !ok || data["order"] != "b" {
+		t.Errorf("expected data decoded from JSON, got %#v", events[1].Data)
+	}
+
+	cursor, _ := fake.GetCursor("orders")
+	if cursor != "2-0" {
+		t.Errorf("expected cursor 2-0, got %q", cursor)
+	}
+
+	if err := fake.TrimChannel("orders", "2-0"); err != nil {
+		t.Fatal(err)
+	}
+	if events := fake.Sent("orders"); len(events) != 1 || events[0].Id != "2-0" {
+		t.Errorf("expected trim to keep 2-0, got %+v", events)
+	}
+}
+
+func TestFakeClientSubscription(t *testing.T) {
+	fake := ritatest.NewFakeClient()
+	fake.SendEvent("test", "old")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	events, err := fake.SubEventSinceContext(ctx, "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.SendEvent("test", "new")
+
+	select {
+	case event := <-events:
+		if event.Data != "new" {
+			t.Errorf("expected only events sent after subscribing, got %v", event.Data)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for the event")
+	}
+
+	replay, _ := fake.SubEventSince("test", "0-0")
+	if event := <-replay; event.Data != "old" {
+		t.Errorf("expected replay from the start, got %v", event.Data)
+	}
+
+	fake.Close()
+	for range replay {
+	}
+}
+
+func TestFakeClientErr(t *testing.T) {
+	fake := ritatest.NewFakeClient()
+	fake.Err = ritago.NotAuthorized
+
+	if _, err := fake.SendEvent("test", "data"); err != ritago.NotAuthorized {
+		t.Errorf("expected the injected error, got %v", err)
+	}
+}
+
+func TestFakeClientSubEventTail(t *testing.T) {
+	fake := ritatest.NewFakeClient()
+	for i := 0; i < 5; i++ {
+		fake.SendEvent("test", i)
+	}
+
+	events, err := fake.SubEventTail("test", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake.SendEvent("test", 5)
+
+	for _, expected := range []string{"4-0", "5-0", "6-0"} {
+		if event := <-events; event.Id != expected {
+			t.Errorf("expected %s, got %s", expected, event.Id)
+		}
+	}
+
+	fake.Close()
+}
+
+func TestFakeClientSubscribe(t *testing.T) {
+	fake := ritatest.NewFakeClient()
+	for i := 0; i < 3; i++ {
+		fake.SendEvent("test", i)
+	}
+
+	var progress []string
+	sub, err := fake.Subscribe(context.Background(), "test", "1-0", ritago.WithOnProgress(func(id string) {
+		progress = append(progress, id)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !sub.IsConnected() {
+		t.Errorf("expected a connected subscription, got %v", sub.State())
+	}
+
+	for _, expected := range []string{"2-0", "3-0"} {
+		select {
+		case event := <-sub.Events():
+			if event.Id != expected {
+				t.Fatalf("expected %s, got %s", expected, event.Id)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("timed out waiting for the event")
+		}
+	}
+
+	sub.Close()
+	for range sub.Events() {
+	}
+
+	if sub.LastID() != "3-0" || len(progress) != 2 || sub.State() != ritago.Closed {
+		t.Errorf("unexpected last id %q, progress %v and state %v", sub.LastID(), progress, sub.State())
+	}
+
+	var states []ritago.ConnectionState
+	for state := range sub.Status() {
+		states = append(states, state)
+	}
+	if len(states) == 0 || states[len(states)-1] != ritago.Closed {
+		t.Errorf("expected the status to end with Closed, got %v", states)
+	}
+}
@@ -0,0 +1,213 @@
+package ritago
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sseFrame is a single server-sent event assembled from the fields that
+// precede a blank line on the stream.
+type sseFrame struct {
+	event string
+	id    string
+	data  string
+	// retry is the reconnection delay suggested by the server, zero when not sent.
+	retry time.Duration
+}
+
+// stream is an open connection to the server delivering frames, read by a
+// Subscription.
+type stream interface {
+	next() (sseFrame, error)
+	Close() error
+}
+
+// sseStream is a stream reading a text/event-stream response body.
+type sseStream struct {
+	*sseReader
+	body io.ReadCloser
+}
+
+func newSseStream(body io.ReadCloser, maxSize int) *sseStream {
+	return &sseStream{sseReader: newSseReader(body, maxSize), body: body}
+}
+
+func (s *sseStream) Close() error {
+	return s.body.Close()
+}
+
+// gzipBody decompresses a gzip encoded stream. The gzip header is read on the
+// first Read, so opening the stream doesn't wait for the server to send data.
+type gzipBody struct {
+	body io.ReadCloser
+	zr   *gzip.Reader
+}
+
+func (b *gzipBody) Read(p []byte) (int, error) {
+	if b.zr == nil {
+		zr, err := gzip.NewReader(b.body)
+		if err != nil {
+			return 0, err
+		}
+		b.zr = zr
+	}
+
+	return b.zr.Read(p)
+}
+
+func (b *gzipBody) Close() error {
+	return b.body.Close()
+}
+
+// sseReader splits a text/event-stream body into frames.
+type sseReader struct {
+	r *bufio.Reader
+	// maxSize bounds a line and the data of a frame; zero means no bound.
+	maxSize int
+}
+
+func newSseReader(r io.Reader, maxSize int) *sseReader {
+	return &sseReader{r: bufio.NewReader(r), maxSize: maxSize}
+}
+
+// next returns the next complete frame. A frame still pending when the stream
+// ends is returned together with the read error. A line or frame larger than
+// maxSize fails with FrameTooLarge, after which the reader must be discarded.
+func (s *sseReader) next() (sseFrame, error) {
+	var frame sseFrame
+	var data []string
+	size := 0
+	pending := false
+
+	for {
+		line, err := s.readLine()
+		if err == FrameTooLarge {
+			return sseFrame{}, err
+		}
+
+		strLine := strings.TrimSpace(string(line))
+
+		if len(line) > 0 && strLine == "" && pending {
+			frame.data = strings.Join(data, "\n")
+			return frame, nil
+		}
+
+		// lines starting with ":" are comments, used by some servers as keep-alives
+		if strLine != "" && !strings.HasPrefix(strLine, ":") {
+			field, value, _ := strings.Cut(strLine, ":")
+			value = strings.TrimSpace(value)
+
+			switch field {
+			case "event":
+				frame.event = value
+				pending = true
+			case "id":
+				frame.id = value
+				pending = true
+			case "data":
+				size += len(value)
+				if s.maxSize > 0 && size > s.maxSize {
+					return sseFrame{}, FrameTooLarge
+				}
+				data = append(data, value)
+				pending = true
+			case "retry":
+				if millis, err := strconv.Atoi(value); err == nil && millis > 0 {
+					frame.retry = time.Duration(millis) * time.Millisecond
+					pending = true
+				}
+			}
+		}
+
+		if err != nil {
+			if !pending {
+				return sseFrame{}, err
+			}
+			frame.data = strings.Join(data, "\n")
+			return frame, err
+		}
+	}
+}
+
+// readLine reads up to and including the next newline, without buffering more
+// than maxSize bytes of it.
+func (s *sseReader) readLine() ([]byte, error) {
+	var line []byte
+
+	for {
+		chunk, err := s.r.ReadSlice('\n')
+
+		if s.maxSize > 0 && len(line)+len(bytes.TrimRight(chunk, "\r\n")) > s.maxSize {
+			return nil, FrameTooLarge
+		}
+
+		line = append(line, chunk...)
+
+		if err != bufio.ErrBufferFull {
+			return line, err
+		}
+	}
+}
+
+// cursor returns the id the stream must be resumed from after the frame's
+// event. The `id:` field of the frame takes precedence over the id of the JSON
+// payload, which is kept on event.Id; a payload without id gets the one of the
+// frame.
+func (f sseFrame) cursor(event *RitaEvent) string {
+	if event.Id == "" {
+		event.Id = f.id
+	}
+
+	if f.id != "" {
+		return f.id
+	}
+
+	return event.Id
+}
+
+// isPing reports whether the frame carries no event for the consumer.
+func (f sseFrame) isPing() bool {
+	return f.event != "error" && (f.data == "" || f.data == "ping")
+}
+
+// serverError returns the error carried by the frame, or nil when the frame is
+// a regular event. Errors are signalled either by an `event: error` frame or
+// by a data payload shaped like {"error": "..."}.
+func (f sseFrame) serverError() error {
+	var payload struct {
+		Error   *string `json:"error"`
+		Message string  `json:"message"`
+	}
+
+	if f.event == "error" {
+		if json.Unmarshal([]byte(f.data), &payload) == nil {
+			if payload.Message != "" {
+				return &ServerError{Message: payload.Message}
+			}
+			if payload.Error != nil && *payload.Error != "" {
+				return &ServerError{Message: *payload.Error}
+			}
+		}
+		return &ServerError{Message: f.data}
+	}
+
+	if !strings.HasPrefix(f.data, "{") {
+		return nil
+	}
+
+	if json.Unmarshal([]byte(f.data), &payload) != nil || payload.Error == nil {
+		return nil
+	}
+
+	if *payload.Error == "" {
+		return &ServerError{Message: payload.Message}
+	}
+
+	return &ServerError{Message: *payload.Error}
+}
@@ -0,0 +1,106 @@
+package ritago
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSseReaderFrames(t *testing.T) {
+	stream := "data: ping\n\n" +
+		": keep-alive\n\n" +
+		"id: 1-0\ndata: {\"id\":\"1-0\"}\n\n" +
+		"data: {\"id\":\"2-0\"}"
+
+	reader := newSseReader(strings.NewReader(stream), 0)
+
+	frame, err := reader.next()
+	if err != nil || !frame.isPing() {
+		t.Fatalf("expected ping frame, got %+v, %v", frame, err)
+	}
+
+	frame, err = reader.next()
+	if err != nil || frame.id != "1-0" || frame.data != `{"id":"1-0"}` {
+		t.Fatalf("unexpected frame %+v, %v", frame, err)
+	}
+
+	frame, err = reader.next()
+	if err != io.EOF || frame.data != `{"id":"2-0"}` {
+		t.Fatalf("expected trailing frame with EOF, got %+v, %v", frame, err)
+	}
+}
+
+func TestSseReaderErrorFrame(t *testing.T) {
+	cases := map[string]string{
+		"event: error\ndata: {\"message\":\"channel closed\"}\n\n": "channel closed",
+		"event: error\ndata: {\"error\":\"channel closed\"}\n\n":   "channel closed",
+		"event: error\ndata: channel closed\n\n":                   "channel closed",
+		"data: {\"error\":\"channel closed\"}\n\n":                 "channel closed",
+	}
+
+	for stream, message := range cases {
+		frame, err := newSseReader(strings.NewReader(stream), 0).next()
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if frame.isPing() {
+			t.Fatalf("%q: error frame treated as ping", stream)
+		}
+
+		var serverErr *ServerError
+		if !errors.As(frame.serverError(), &serverErr) {
+			t.Fatalf("%q: expected ServerError, got %v", stream, frame.serverError())
+		}
+
+		if serverErr.Message != message {
+			t.Errorf("%q: expected message %q, got %q", stream, message, serverErr.Message)
+		}
+	}
+}
+
+func TestSseReaderEventIsNotError(t *testing.T) {
+	frame, err := newSseReader(strings.NewReader("data: {\"id\":\"1-0\",\"data\":{\"error\":\"x\"}}\n\n"), 0).next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if frame.serverError() != nil {
+		t.Errorf("event with nested error field reported as server error")
+	}
+}
+
+func TestSseReaderRetryHint(t *testing.T) {
+	frame, err := newSseReader(strings.NewReader("retry: 2500\n\n"), 0).next()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if frame.retry != 2500*time.Millisecond || !frame.isPing() {
+		t.Errorf("expected a 2.5s retry hint without event, got %+v", frame)
+	}
+}
+
+func TestSseReaderMaxSize(t *testing.T) {
+	streams := map[string]string{
+		"line":  "data: " + strings.Repeat("x", 64) + "\n\n",
+		"frame": strings.Repeat("data: "+strings.Repeat("x", 10)+"\n", 8) + "\n",
+	}
+
+	for name, stream := range streams {
+		if _, err := newSseReader(strings.NewReader(stream), 32).next(); err != FrameTooLarge {
+			t.Errorf("%s: expected FrameTooLarge, got %v", name, err)
+		}
+
+		if _, err := newSseReader(strings.NewReader(stream), 0).next(); err != nil {
+			t.Errorf("%s: expected no bound, got %v", name, err)
+		}
+	}
+
+	frame, err := newSseReader(strings.NewReader("data: "+strings.Repeat("x", 26)+"\r\n\r\n"), 32).next()
+	if err != nil || len(frame.data) != 26 {
+		t.Errorf("expected a frame of exactly the maximum size, got %q %v", frame.data, err)
+	}
+}
@@ -0,0 +1,391 @@
+package ritago
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SubscribeOption configures a Subscription opened with Subscribe.
+type SubscribeOption func(*Subscription)
+
+// WithOnProgress calls onProgress with the id of every event, as reported by
+// LastID, once the consumer received it from Events(), so the id can be
+// checkpointed and the consumer resumed later with SubEventSince. The hook runs
+// on the goroutine delivering the events: the next event is delivered after it
+// returns.
+func WithOnProgress(onProgress func(id string)) SubscribeOption {
+	return func(s *Subscription) {
+		s.onProgress = onProgress
+	}
+}
+
+// EventSource feeds a Subscription created with NewSubscription, e.g. a fake of the
+// server in tests. Next blocks until the next event is available and returns io.EOF
+// once there are no more events; any other error is delivered on Errors() and ends the
+// subscription. Close is called when the subscription ends and must unblock Next.
+type EventSource interface {
+	Next() (*RitaEvent, error)
+	Close() error
+}
+
+// NewSubscription returns a Subscription delivering the events of source until it ends,
+// ctx is done or the subscription is closed, so code working with a *Subscription, as
+// returned by Subscribe, can be tested without a server. It starts Connected and never
+// reconnects; the progress of LastID and WithOnProgress works as for Subscribe.
+func NewSubscription(ctx context.Context, source EventSource, opts ...SubscribeOption) *Subscription {
+	sub := newSubscription(slog.New(slog.NewTextHandler(io.Discard, nil)))
+	for _, opt := range opts {
+		opt(sub)
+	}
+	sub.setState(Connecting)
+
+	sub.stream = &sourceStream{source: source}
+	sub.setState(Connected)
+
+	go sub.consume(ctx)
+
+	return sub
+}
+
+// sourceStream is a stream reading the events of an EventSource.
+type sourceStream struct {
+	source EventSource
+}
+
+func (s *sourceStream) next() (sseFrame, error) {
+	event, err := s.source.Next()
+	if err != nil || event == nil {
+		return sseFrame{}, err
+	}
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return sseFrame{}, err
+	}
+
+	return sseFrame{id: event.Id, data: string(data)}, nil
+}
+
+func (s *sourceStream) Close() error {
+	return s.source.Close()
+}
+
+// ConnectionState is the state of the connection of a Subscription.
+type ConnectionState int
+
+const (
+	// Connecting: the subscription is opening the stream.
+	Connecting ConnectionState = iota
+	// Connected: the stream is established and events are being received.
+	Connected
+	// Reconnecting: the stream was lost and the subscription is opening it again.
+	Reconnecting
+	// Closed: the subscription ended and no more events will be delivered.
+	Closed
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Connecting:
+		return "connecting"
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	case Closed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// Subscription is a live stream of events from a channel.
+//
+// Events are delivered on Events(). Errors reported by the server or found
+// while reading the stream are delivered on Errors(); the errors channel is
+// buffered and errors are dropped when nobody reads it, so consumers that
+// only care about events may ignore it.
+//
+// Changes of the connection state are delivered on Status(), starting with
+// Connecting and Connected and ending with Closed, which makes it simple to
+// drive a "live" indicator. When the status channel is full the oldest state
+// is discarded, so a slow reader always ends up seeing the latest one.
+//
+// When the client is configured to reconnect, a lost stream is opened again
+// from the last delivered event, reporting Reconnecting while it does. The
+// position in the stream is tracked with the SSE `id:` field of the frames,
+// falling back to the Id of the event when the field is missing. With
+// ReconnectDedupe, a reopened stream starting with that event again skips it.
+//
+// All channels are closed when the stream ends or the subscription is closed.
+type Subscription struct {
+	events chan *RitaEvent
+	errors chan error
+	status chan ConnectionState
+
+	// state is the current ConnectionState, stored by setState.
+	state atomic.Int32
+
+	done      chan struct{}
+	closeOnce sync.Once
+	mu        sync.Mutex
+	stream    stream
+
+	// open opens the stream again from eventId; reconnect is nil when the
+	// subscription must end with the stream.
+	open      func(ctx context.Context, eventId string) (stream, error)
+	reconnect *reconnectPolicy
+	resumeId  string
+	retryHint time.Duration
+	// seam is set when the stream was reopened and its first event, if it repeats
+	// resumeId, must be dropped.
+	seam bool
+
+	// delivered is the id of the last event received by the consumer, guarded by mu.
+	delivered  string
+	onProgress func(id string)
+
+	logger *slog.Logger
+}
+
+const (
+	subscriptionErrorsBuffer = 16
+	subscriptionStatusBuffer = 8
+)
+
+func newSubscription(logger *slog.Logger) *Subscription {
+	return &Subscription{
+		events: make(chan *RitaEvent),
+		errors: make(chan error, subscriptionErrorsBuffer),
+		status: make(chan ConnectionState, subscriptionStatusBuffer),
+		done:   make(chan struct{}),
+		logger: logger,
+	}
+}
+
+// Events returns the channel on which the subscription delivers events.
+func (s *Subscription) Events() <-chan *RitaEvent {
+	return s.events
+}
+
+// Errors returns the channel on which the subscription delivers errors.
+func (s *Subscription) Errors() <-chan error {
+	return s.errors
+}
+
+// Status returns the channel on which the subscription delivers the changes of its connection state.
+func (s *Subscription) Status() <-chan ConnectionState {
+	return s.status
+}
+
+// State returns the current state of the connection of the subscription. It is
+// cheap and safe to call from any goroutine, e.g. from a health endpoint.
+func (s *Subscription) State() ConnectionState {
+	select {
+	case <-s.done:
+		return Closed
+	default:
+	}
+
+	return ConnectionState(s.state.Load())
+}
+
+// IsConnected reports whether the stream of the subscription is established.
+// It is false while connecting, reconnecting and once the subscription ended.
+func (s *Subscription) IsConnected() bool {
+	return s.State() == Connected
+}
+
+// LastID returns the id of the last event received from Events(), empty until the
+// first one. Saved, it lets a restarted consumer resume with SubEventSince.
+//
+// When the server sends an SSE `id:` field, that id is reported and used to
+// reconnect, even if it differs from the Id of the JSON payload of the event.
+func (s *Subscription) LastID() string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.delivered
+}
+
+// progress records id as delivered and reports it to the OnProgress hook.
+func (s *Subscription) progress(id string) {
+	s.mu.Lock()
+	s.delivered = id
+	s.mu.Unlock()
+
+	if s.onProgress != nil {
+		s.onProgress(id)
+	}
+}
+
+// Close ends the subscription and releases its connection. Events not yet
+// received are discarded. It is safe to call Close more than once.
+func (s *Subscription) Close() {
+	s.closeOnce.Do(func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+
+		close(s.done)
+		if s.stream != nil {
+			s.stream.Close()
+		}
+	})
+}
+
+// setStream replaces the stream, closing the previous one. It reports false,
+// closing stream, when the subscription was closed in the meantime.
+func (s *Subscription) setStream(stream stream) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	select {
+	case <-s.done:
+		stream.Close()
+		return false
+	default:
+	}
+
+	if s.stream != nil {
+		s.stream.Close()
+	}
+
+	s.stream = stream
+	return true
+}
+
+func (s *Subscription) currentStream() stream {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.stream
+}
+
+// setState records state and reports it on the status channel. It is only
+// called by the goroutine owning the subscription, so making room by
+// discarding the oldest state can't race with another sender.
+func (s *Subscription) setState(state ConnectionState) {
+	s.state.Store(int32(state))
+
+	for {
+		select {
+		case s.status <- state:
+			return
+		default:
+		}
+
+		select {
+		case <-s.status:
+		default:
+		}
+	}
+}
+
+func (s *Subscription) sendError(err error) {
+	select {
+	case s.errors <- err:
+	default:
+		s.logger.Warn("rita subscription error dropped", "error", err)
+	}
+}
+
+// consume delivers the events of the stream until it ends, ctx is cancelled
+// or the subscription is closed, reconnecting in between when configured to,
+// then closes the stream and all channels.
+func (s *Subscription) consume(ctx context.Context) {
+	defer func() {
+		s.currentStream().Close()
+		s.setState(Closed)
+		close(s.events)
+		close(s.errors)
+		close(s.status)
+	}()
+
+	for {
+		err := s.read(ctx)
+
+		if s.stopped(ctx) {
+			return
+		}
+
+		if err != nil {
+			s.sendError(err)
+		}
+
+		if s.reconnect == nil || !s.redial(ctx) {
+			return
+		}
+	}
+}
+
+// read delivers the events of the current stream until it ends, returning the
+// error that ended it; nil when the server closed the stream.
+func (s *Subscription) read(ctx context.Context) error {
+	stream := s.currentStream()
+
+	for {
+		frame, err := stream.next()
+
+		if s.stopped(ctx) {
+			return nil
+		}
+
+		if frame.retry > 0 {
+			s.retryHint = frame.retry
+		}
+
+		if frame.isPing() {
+			// nothing to deliver
+		} else if serverErr := frame.serverError(); serverErr != nil {
+			s.sendError(serverErr)
+		} else {
+			var event RitaEvent
+			if jsonErr := json.Unmarshal([]byte(frame.data), &event); jsonErr != nil {
+				s.sendError(fmt.Errorf("%w: %v", JsonNotValid, jsonErr))
+			} else {
+				cursor := frame.cursor(&event)
+
+				repeated := s.seam && cursor == s.resumeId
+				s.seam = false
+
+				if repeated {
+					s.logger.Debug("rita subscription dropped the redelivered event", "id", cursor)
+				} else {
+					select {
+					case s.events <- &event:
+						s.resumeId = cursor
+						s.progress(cursor)
+					case <-s.done:
+						return nil
+					case <-ctx.Done():
+						return nil
+					}
+				}
+			}
+		}
+
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+	}
+}
+
+func (s *Subscription) stopped(ctx context.Context) bool {
+	select {
+	case <-s.done:
+		return true
+	case <-ctx.Done():
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,499 @@
+package ritago
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func newStreamServer(t *testing.T, stream string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, stream)
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubscribeRoutesErrorFrames(t *testing.T) {
+	server := newStreamServer(t, "data: {\"id\":\"1-0\"}\n\n"+
+		"event: error\ndata: {\"message\":\"boom\"}\n\n"+
+		"data: {\"id\":\"2-0\"}\n\n")
+
+	client := NewRitaClient(&RitaConfig{Url: server.URL, ApiKey: "key"})
+
+	sub, err := client.Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for event := range sub.Events() {
+		ids = append(ids, event.Id)
+	}
+
+	if len(ids) != 2 || ids[0] != "1-0" || ids[1] != "2-0" {
+		t.Errorf("unexpected events %v", ids)
+	}
+
+	var serverErr *ServerError
+	err = <-sub.Errors()
+	if !errors.As(err, &serverErr) || serverErr.Message != "boom" {
+		t.Errorf("expected server error boom, got %v", err)
+	}
+
+	if _, ok := <-sub.Errors(); ok {
+		t.Errorf("expected errors channel to be closed")
+	}
+}
+
+func newEndlessStreamServer(t *testing.T) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for i := 0; ; i++ {
+			select {
+			case <-r.Context().Done():
+				return
+			case <-time.After(time.Millisecond):
+			}
+			if _, err := fmt.Fprintf(w, "data: {\"id\":\"%d-0\"}\n\n", i); err != nil {
+				return
+			}
+			w.(http.Flusher).Flush()
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func waitGoroutines(t *testing.T, max int) {
+	t.Helper()
+
+	deadline := time.Now().Add(2 * time.Second)
+	for runtime.NumGoroutine() > max {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<16)
+			t.Fatalf("goroutines leaked: %d > %d\n%s", runtime.NumGoroutine(), max, buf[:runtime.Stack(buf, true)])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestSubEventSinceContextDoesNotLeak(t *testing.T) {
+	server := newEndlessStreamServer(t)
+	client := NewRitaClient(&RitaConfig{Url: server.URL, ApiKey: "key"})
+
+	before := runtime.NumGoroutine()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	events, err := client.SubEventSinceContext(ctx, "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// receive one event so the reader is blocked sending the next one
+	<-events
+	time.Sleep(20 * time.Millisecond)
+
+	cancel()
+
+	waitGoroutines(t, before)
+}
+
+func TestSubscriptionCloseDoesNotLeak(t *testing.T) {
+	server := newEndlessStreamServer(t)
+	client := NewRitaClient(&RitaConfig{Url: server.URL, ApiKey: "key"})
+
+	before := runtime.NumGoroutine()
+
+	sub, err := client.Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+
+	sub.Close()
+	sub.Close()
+
+	waitGoroutines(t, before)
+
+	if _, ok := <-sub.Events(); ok {
+		t.Errorf("expected events channel to be closed")
+	}
+}
+
+func TestSubscribeInheritsUserTransport(t *testing.T) {
+	server := newStreamServer(t, "data: {\"id\":\"1-0\"}\n\n")
+
+	var dials int32
+	base := &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			atomic.AddInt32(&dials, 1)
+			return (&net.Dialer{}).DialContext(ctx, network, addr)
+		},
+	}
+
+	client := New(server.URL, "key", WithHTTPClient(&http.Client{Transport: base}))
+
+	transport, ok := client.sseClient.Transport.(*http.Transport)
+	if !ok || transport == base || !transport.DisableCompression {
+		t.Fatalf("expected a clone of the user transport with compression off")
+	}
+
+	if base.DisableCompression {
+		t.Errorf("the user transport must not be modified")
+	}
+
+	for i := 0; i < 2; i++ {
+		sub, err := client.Subscribe(context.Background(), "test", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		for range sub.Events() {
+		}
+	}
+
+	if client.sseClient.Transport != transport {
+		t.Errorf("expected the subscription transport to be reused")
+	}
+
+	if atomic.LoadInt32(&dials) == 0 {
+		t.Errorf("expected the user dialer to be used by the subscription")
+	}
+}
+
+func TestSubscriptionStatus(t *testing.T) {
+	server := newStreamServer(t, "data: {\"id\":\"1-0\"}\n\n")
+	client := New(server.URL, "key")
+
+	sub, err := client.Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for range sub.Events() {
+	}
+
+	var states []ConnectionState
+	for state := range sub.Status() {
+		states = append(states, state)
+	}
+
+	expected := []ConnectionState{Connecting, Connected, Closed}
+	if fmt.Sprint(states) != fmt.Sprint(expected) {
+		t.Errorf("expected states %v, got %v", expected, states)
+	}
+}
+
+func TestSubscriptionStatusKeepsLatest(t *testing.T) {
+	sub := newSubscription(New("", "").logger)
+
+	for i := 0; i < subscriptionStatusBuffer*2; i++ {
+		sub.setState(Connected)
+	}
+	sub.setState(Closed)
+
+	var last ConnectionState
+	for len(sub.status) > 0 {
+		last = <-sub.status
+	}
+
+	if last != Closed {
+		t.Errorf("expected the latest state to be kept, got %v", last)
+	}
+}
+
+func TestSubEventTail(t *testing.T) {
+	var since string
+
+	base := newPagedServer(t, 15, 0, false, new(int))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("sub") != "true" {
+			base.Config.Handler.ServeHTTP(w, r)
+			return
+		}
+
+		// an inclusive server repeats the event the stream starts from
+		since = r.URL.Query().Get("eventId")
+		fmt.Fprintf(w, "data: {\"id\":%q}\n\n", since)
+		fmt.Fprint(w, "data: {\"id\":\"16-0\"}\n\ndata: {\"id\":\"17-0\"}\n\n")
+	}))
+	defer server.Close()
+
+	events, err := New(server.URL, "key").SubEventTail("test", 3)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for event := range events {
+		ids = append(ids, event.Id)
+	}
+
+	if since != "15-0" {
+		t.Errorf("expected the live stream to start from 15-0, got %q", since)
+	}
+
+	if fmt.Sprint(ids) != "[13-0 14-0 15-0 16-0 17-0]" {
+		t.Errorf("unexpected events %v", ids)
+	}
+}
+
+func TestSubscriptionProgress(t *testing.T) {
+	server := newStreamServer(t, "data: {\"id\":\"1-0\"}\n\ndata: {\"id\":\"2-0\"}\n\ndata: {\"id\":\"3-0\"}\n\n")
+
+	var progress []string
+
+	sub, err := New(server.URL, "key").Subscribe(context.Background(), "test", "", WithOnProgress(func(id string) {
+		progress = append(progress, id)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if sub.LastID() != "" {
+		t.Errorf("expected no last id before receiving, got %q", sub.LastID())
+	}
+
+	event := <-sub.Events()
+
+	// the reader may already hold the next event, but it is not handed over yet
+	time.Sleep(20 * time.Millisecond)
+	if sub.LastID() != event.Id {
+		t.Errorf("expected last id %q, got %q", event.Id, sub.LastID())
+	}
+
+	for range sub.Events() {
+	}
+
+	if sub.LastID() != "3-0" || fmt.Sprint(progress) != "[1-0 2-0 3-0]" {
+		t.Errorf("unexpected last id %q and progress %v", sub.LastID(), progress)
+	}
+}
+
+func TestSubscriptionPrefersSseId(t *testing.T) {
+	var since []string
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		since = append(since, r.URL.Query().Get("eventId"))
+		if len(since) > 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		fmt.Fprint(w, "id: 5-0\ndata: {\"id\":\"payload-5\"}\n\n")
+		fmt.Fprint(w, "id: 6-0\ndata: {\"data\":\"no id\"}\n\n")
+		fmt.Fprint(w, "data: {\"id\":\"7-0\"}\n\n")
+		fmt.Fprint(w, "id: 8-0\ndata: {\"id\":\"payload-8\"}\n\n")
+	}))
+	defer server.Close()
+
+	var progress []string
+
+	client := New(server.URL, "key", WithReconnect(time.Millisecond, time.Millisecond))
+	sub, err := client.Subscribe(context.Background(), "test", "", WithOnProgress(func(id string) {
+		progress = append(progress, id)
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for event := range sub.Events() {
+		ids = append(ids, event.Id)
+	}
+
+	if fmt.Sprint(ids) != "[payload-5 6-0 7-0 payload-8]" {
+		t.Errorf("expected the payload ids on the events, got %v", ids)
+	}
+
+	if fmt.Sprint(progress) != "[5-0 6-0 7-0 8-0]" || sub.LastID() != "8-0" {
+		t.Errorf("expected the SSE ids as cursor, got %v and last %q", progress, sub.LastID())
+	}
+
+	if fmt.Sprint(since) != "[ 8-0]" {
+		t.Errorf("expected to resume from the SSE id, got %q", since)
+	}
+}
+
+func TestSubscriptionCompression(t *testing.T) {
+	received := make(chan struct{})
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Accept-Encoding") != "gzip" {
+			w.Header().Set("Content-Type", "text/event-stream")
+			fmt.Fprint(w, "data: {\"id\":\"plain\"}\n\n")
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Content-Encoding", "gzip")
+
+		zw := gzip.NewWriter(w)
+		flush := func() {
+			zw.Flush()
+			w.(http.Flusher).Flush()
+		}
+
+		fmt.Fprint(zw, "data: {\"id\":\"1-0\"}\n\n")
+		flush()
+
+		// the first event must reach the client before the stream goes on
+		select {
+		case <-received:
+		case <-time.After(time.Second):
+			return
+		}
+
+		fmt.Fprint(zw, "data: {\"id\":")
+		flush()
+		fmt.Fprint(zw, "\"2-0\"}\n\n")
+		zw.Close()
+	}))
+	defer server.Close()
+
+	sub, err := New(server.URL, "key", WithSubscriptionCompression(true)).Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event := <-sub.Events(); event == nil || event.Id != "1-0" {
+		t.Fatalf("expected 1-0 before the end of the stream, got %+v", event)
+	}
+	close(received)
+
+	if event := <-sub.Events(); event == nil || event.Id != "2-0" {
+		t.Fatalf("expected the frame split across flushes, got %+v", event)
+	}
+	if event, ok := <-sub.Events(); ok {
+		t.Errorf("expected the stream to end, got %+v", event)
+	}
+
+	sub, err = New(server.URL, "key").Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if event := <-sub.Events(); event == nil || event.Id != "plain" {
+		t.Errorf("expected an identity stream by default, got %+v", event)
+	}
+}
+
+func TestSubscriptionState(t *testing.T) {
+	drop := make(chan struct{})
+	var connections int32
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&connections, 1) > 1 {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "data: {\"id\":\"1-0\"}\n\n")
+		w.(http.Flusher).Flush()
+		<-drop
+	}))
+	defer server.Close()
+
+	client := New(server.URL, "key", WithReconnect(100*time.Millisecond, 100*time.Millisecond))
+
+	sub, err := client.Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	<-sub.Events()
+	if !sub.IsConnected() || sub.State() != Connected {
+		t.Errorf("expected a connected subscription, got %v", sub.State())
+	}
+
+	close(drop)
+
+	waitState := func(expected ConnectionState) {
+		deadline := time.Now().Add(time.Second)
+		for sub.State() != expected && time.Now().Before(deadline) {
+			time.Sleep(time.Millisecond)
+		}
+		if state := sub.State(); state != expected {
+			t.Errorf("expected %v, got %v", expected, state)
+		}
+	}
+
+	waitState(Reconnecting)
+	if sub.IsConnected() {
+		t.Errorf("expected a reconnecting subscription not to be connected")
+	}
+
+	// the reconnection is refused
+	waitState(Closed)
+
+	sub, err = New(newEndlessStreamServer(t).URL, "key").Subscribe(context.Background(), "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sub.Close()
+	if sub.State() != Closed {
+		t.Errorf("expected Close to be reflected at once, got %v", sub.State())
+	}
+}
+
+type sliceSource struct {
+	events []*RitaEvent
+	err    error
+	closed int32
+}
+
+func (s *sliceSource) Next() (*RitaEvent, error) {
+	if len(s.events) == 0 {
+		return nil, s.err
+	}
+
+	event := s.events[0]
+	s.events = s.events[1:]
+	return event, nil
+}
+
+func (s *sliceSource) Close() error {
+	atomic.AddInt32(&s.closed, 1)
+	return nil
+}
+
+func TestNewSubscription(t *testing.T) {
+	boom := errors.New("boom")
+	source := &sliceSource{
+		events: []*RitaEvent{{Id: "1-0", Data: "a"}, {Id: "2-0", Data: "b"}},
+		err:    boom,
+	}
+
+	sub := NewSubscription(context.Background(), source)
+
+	var data []interface{}
+	for event := range sub.Events() {
+		data = append(data, event.Data)
+	}
+
+	if fmt.Sprint(data) != "[a b]" || sub.LastID() != "2-0" {
+		t.Errorf("unexpected events %v, last id %q", data, sub.LastID())
+	}
+
+	if err := <-sub.Errors(); err != boom {
+		t.Errorf("expected the error of the source, got %v", err)
+	}
+
+	if atomic.LoadInt32(&source.closed) == 0 || sub.State() != Closed {
+		t.Errorf("expected the source to be closed with the subscription")
+	}
+}
@@ -1,6 +1,14 @@
 package ritago
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -16,6 +24,74 @@ type RitaConfig struct {
 	Url    string
 	ApiKey string
 	//LogInConsole bool
+
+	// Timeout limits the duration of the unary requests. Subscriptions are not affected.
+	Timeout time.Duration
+	// HTTPClient is used for the unary requests instead of a default client.
+	HTTPClient *http.Client
+	// Logger receives the diagnostics of the client. Nothing is logged when nil.
+	Logger *slog.Logger
+	// MaxRetries is the number of times a failed unary request is retried. Sends are only
+	// retried when made with SendEventWithKey, since a send that failed on the way back may
+	// have created the event, and retrying it without a key would publish it twice.
+	MaxRetries int
+	// RetryBackoff is the delay before the first retry, doubled on every attempt.
+	RetryBackoff time.Duration
+	// DisableCompression stops the unary requests from asking for gzip encoded responses,
+	// saving the cost of decompressing them on constrained CPUs.
+	DisableCompression bool
+	// SubscriptionCompression lets the subscriptions ask for a gzip encoded stream, saving
+	// bandwidth on verbose events. It is off by default: a proxy or load balancer between the
+	// client and the server may buffer a compressed stream until a whole block is available,
+	// delaying the events, so enable it only when the server flushes the compressed stream
+	// after every event and nothing in between buffers it.
+	SubscriptionCompression bool
+	// AuthHeader is the header carrying the api key. Defaults to Authorization.
+	AuthHeader string
+	// AuthScheme prefixes the api key in the auth header, e.g. "Bearer". Empty sends the raw key.
+	AuthScheme string
+	// Reconnect makes the subscriptions open their stream again, from the last delivered
+	// event, when it is lost.
+	Reconnect bool
+	// ReconnectMinDelay and ReconnectMaxDelay bound the jittered delay between reconnection
+	// attempts. They default to 500ms and 30s. A retry hint sent by the server replaces
+	// ReconnectMinDelay as the base of the backoff, within the bounds.
+	ReconnectMinDelay time.Duration
+	ReconnectMaxDelay time.Duration
+	// ReconnectDedupe drops the first event of a reopened stream when its id is the one of
+	// the last delivered event, for servers resuming from an id inclusively. It only guards
+	// the reconnection boundary: it is not a cache, and other duplicates are delivered.
+	ReconnectDedupe bool
+	// ProxyURL routes the unary requests and the subscriptions through a proxy, instead of
+	// the one of the HTTP_PROXY environment variables. Supported schemes are http, https and
+	// socks5. A malformed url makes every request fail with ProxyUrlNotValid.
+	ProxyURL string
+	// MaxConcurrentSends bounds the unary requests in flight at once; further requests wait
+	// for a slot, or for their context to be done. Zero means no bound.
+	MaxConcurrentSends int
+	// RequestDecorator is called with the context of the call right before every request is
+	// sent, including the subscriptions and their reconnections, e.g. to inject tracing headers.
+	RequestDecorator func(ctx context.Context, req *http.Request)
+	// AllowNullPayload lets SendEvent send data that marshals to the JSON null, such as nil
+	// or a nil pointer, map or slice, instead of failing with EmptyPayload.
+	AllowNullPayload bool
+	// MaxFrameSize bounds the size in bytes of a line and of the data of a frame read from a
+	// subscription, and of a WebSocket message. A larger one is reported as FrameTooLarge on
+	// the errors channel and ends the stream, which is opened again when reconnecting.
+	// Zero means 1 MiB; a negative size means no bound.
+	MaxFrameSize int
+	// ResponseHook is called after every request with its method, channel, status and duration,
+	// e.g. for audit logging: once per attempt of the unary requests, after the body was read,
+	// and when the stream of a subscription is established, including its reconnections. The
+	// status is zero when no response was received.
+	ResponseHook func(method, channel string, status int, dur time.Duration)
+	// LatestEventsParam names the query param with which the server returns the last n events
+	// of a channel, oldest first, in a single request, e.g. "last" for ?last=10. When empty,
+	// GetLatestEvents and SubEventTail read the channel forward up to its tail, which costs a
+	// request per page of the channel.
+	LatestEventsParam string
+	// PingPath is the path of the route answering Ping and WaitForReady. Defaults to /v1/ping.
+	PingPath string
 }
 
 // RESPONSE TYPES
@@ -28,6 +104,126 @@ type RitaEvent struct {
 	Id        string
 	CreatedAt time.Time
 	Data      any
+
+	// data is the JSON of Data as received, decoded again by Decode. It is a string so
+	// RitaEvent stays comparable.
+	data string
+}
+
+// UnmarshalJSON decodes an event accepting CreatedAt either as an RFC3339 string or as an
+// epoch timestamp, in seconds or milliseconds, given as a number or a string of digits.
+// CreatedAt is normalized to UTC and left as the zero time when it is missing, null or empty.
+func (e *RitaEvent) UnmarshalJSON(b []byte) error {
+	var raw struct {
+		Id        string
+		CreatedAt json.RawMessage
+		Data      json.RawMessage
+	}
+
+	if err := json.Unmarshal(b, &raw); err != nil {
+		return err
+	}
+
+	createdAt, err := parseCreatedAt(raw.CreatedAt)
+	if err != nil {
+		return err
+	}
+
+	var data any
+	if len(raw.Data) > 0 {
+		if err := json.Unmarshal(raw.Data, &data); err != nil {
+			return err
+		}
+	}
+
+	e.Id = raw.Id
+	e.CreatedAt = createdAt
+	e.Data = data
+	e.data = string(raw.Data)
+
+	return nil
+}
+
+// Decode unmarshals the data of the event into target, like json.Unmarshal. An event
+// received from the server is decoded from the JSON it was received with, so changes
+// made to its Data since are not seen; the Data of any other event is marshaled first.
+//
+// Parameters:
+//   - target: A pointer to the value receiving the data.
+//
+// Returns:
+//   - error: DataNotDecodable, wrapping the error of encoding/json, when the data doesn't
+//     fit target.
+//
+// Example:
+//
+//	var order Order
+//	if err := event.Decode(&order); err != nil {
+//		return err
+//	}
+func (e *RitaEvent) Decode(target interface{}) error {
+	data := []byte(e.data)
+	if e.data == "" {
+		var err error
+		if data, err = json.Marshal(e.Data); err != nil {
+			return fmt.Errorf("%w: %w", DataNotDecodable, err)
+		}
+	}
+
+	if err := json.Unmarshal(data, target); err != nil {
+		return fmt.Errorf("%w: %w", DataNotDecodable, err)
+	}
+
+	return nil
+}
+
+// epochMillisThreshold separates epoch timestamps in seconds from the ones in
+// milliseconds: 1e11 seconds is far in the future, 1e11 milliseconds is 1973.
+const epochMillisThreshold = 1e11
+
+func parseCreatedAt(raw json.RawMessage) (time.Time, error) {
+	value := strings.TrimSpace(string(raw))
+
+	if value == "" || value == "null" || value == `""` {
+		return time.Time{}, nil
+	}
+
+	if strings.HasPrefix(value, `"`) {
+		if err := json.Unmarshal(raw, &value); err != nil {
+			return time.Time{}, err
+		}
+		value = strings.TrimSpace(value)
+
+		if value == "" {
+			return time.Time{}, nil
+		}
+
+		if t, err := time.Parse(time.RFC3339, value); err == nil {
+			return t.UTC(), nil
+		}
+	}
+
+	whole, fraction, _ := strings.Cut(value, ".")
+
+	epoch, err := strconv.ParseInt(whole, 10, 64)
+	if err == nil && fraction != "" {
+		_, err = strconv.ParseUint(fraction, 10, 64)
+	}
+	if err != nil {
+		return time.Time{}, fmt.Errorf("createdAt %s is neither an RFC3339 time nor an epoch timestamp", raw)
+	}
+
+	if epoch >= epochMillisThreshold || epoch <= -epochMillisThreshold {
+		return time.UnixMilli(epoch).UTC(), nil
+	}
+
+	// the fraction of a second is read as nanoseconds, without float rounding
+	nanos, _ := strconv.ParseInt((fraction + "000000000")[:9], 10, 64)
+	if strings.HasPrefix(whole, "-") {
+		nanos = -nanos
+	}
+
+	return time.Unix(epoch, nanos).UTC(), nil
 }
 
 // ERROR
@@ -43,6 +239,14 @@ const (
 	NotAuthorized
 	Forbidden
 	UnknownError
+	EventIdNotValid
+	ProxyUrlNotValid
+	NotFound
+	EmptyPayload
+	FrameTooLarge
+	CreatedAtNotAccepted
+	DataNotDecodable
+	TailNotFound
 )
 
 func (e ritaError) String() string {
@@ -50,19 +254,35 @@ func (e ritaError) String() string {
 	case ChannelNotValid:
 		return "the channel name is not valid"
 	case ServerNotConfig:
-		return "the server url is not setted"
+		return "the server url is not set"
 	case ApikeyNotConfig:
-		return "the apikey is not setted"
+		return "the apikey is not set"
 	case JsonNotValid:
-		return "the object sent is not a json"
+		return "the object sent is not valid json"
 	case ServerUrlNotValid:
 		return "the server url is not valid"
 	case NotAuthorized:
 		return "not authorized"
 	case Forbidden:
-		return "Forbidden"
-	case UnknownError:
 		return "forbidden"
+	case UnknownError:
+		return "unknown error"
+	case EventIdNotValid:
+		return "the event id is not valid"
+	case ProxyUrlNotValid:
+		return "the proxy url is not valid"
+	case NotFound:
+		return "not found"
+	case EmptyPayload:
+		return "the event data is empty"
+	case FrameTooLarge:
+		return "the frame exceeds the maximum size"
+	case CreatedAtNotAccepted:
+		return "the server does not accept the event time"
+	case DataNotDecodable:
+		return "the event data does not match the target"
+	case TailNotFound:
+		return "the last event of the channel was not found"
 	default:
 		return "unknown error"
 	}
@@ -71,3 +291,66 @@ func (e ritaError) String() string {
 func (e ritaError) Error() string {
 	return e.String()
 }
+
+// Is makes NotFound match Forbidden, which 404 responses were reported as before
+// NotFound existed, so errors.Is(err, Forbidden) keeps holding for both.
+func (e ritaError) Is(target error) bool {
+	return e == NotFound && target == Forbidden
+}
+
+// ChannelErrors maps the channels of a batch request that failed to their error.
+type ChannelErrors map[string]error
+
+func (e ChannelErrors) Error() string {
+	channels := make([]string, 0, len(e))
+	for channel := range e {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+
+	messages := make([]string, len(channels))
+	for i, channel := range channels {
+		messages[i] = channel + ": " + e[channel].Error()
+	}
+
+	return fmt.Sprintf("%d channels failed: %s", len(e), strings.Join(messages, "; "))
+}
+
+// Unwrap returns the errors of the channels, so errors.Is matches any of them.
+func (e ChannelErrors) Unwrap() []error {
+	errs := make([]error, 0, len(e))
+	for _, err := range e {
+		errs = append(errs, err)
+	}
+
+	return errs
+}
+
+// Sentinel errors, to be compared with errors.Is.
+var (
+	ErrChannelNotValid      error = ChannelNotValid
+	ErrServerNotConfig      error = ServerNotConfig
+	ErrApikeyNotConfig      error = ApikeyNotConfig
+	ErrJsonNotValid         error = JsonNotValid
+	ErrServerUrlNotValid    error = ServerUrlNotValid
+	ErrNotAuthorized        error = NotAuthorized
+	ErrForbidden            error = Forbidden
+	ErrUnknownError         error = UnknownError
+	ErrEventIdNotValid      error = EventIdNotValid
+	ErrProxyUrlNotValid     error = ProxyUrlNotValid
+	ErrNotFound             error = NotFound
+	ErrEmptyPayload         error = EmptyPayload
+	ErrFrameTooLarge        error = FrameTooLarge
+	ErrCreatedAtNotAccepted error = CreatedAtNotAccepted
+	ErrDataNotDecodable     error = DataNotDecodable
+	ErrTailNotFound         error = TailNotFound
+)
+
+// ServerError is an error reported by the server on an open subscription.
+type ServerError struct {
+	Message string
+}
+
+func (e *ServerError) Error() string {
+	return "server error: " + e.Message
+}
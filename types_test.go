@@ -0,0 +1,154 @@
+package ritago
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestRitaErrorMessages(t *testing.T) {
+	messages := map[ritaError]string{
+		ChannelNotValid:      "the channel name is not valid",
+		ServerNotConfig:      "the server url is not set",
+		ApikeyNotConfig:      "the apikey is not set",
+		JsonNotValid:         "the object sent is not valid json",
+		ServerUrlNotValid:    "the server url is not valid",
+		NotAuthorized:        "not authorized",
+		Forbidden:            "forbidden",
+		UnknownError:         "unknown error",
+		EventIdNotValid:      "the event id is not valid",
+		ProxyUrlNotValid:     "the proxy url is not valid",
+		NotFound:             "not found",
+		EmptyPayload:         "the event data is empty",
+		FrameTooLarge:        "the frame exceeds the maximum size",
+		CreatedAtNotAccepted: "the server does not accept the event time",
+		DataNotDecodable:     "the event data does not match the target",
+		TailNotFound:         "the last event of the channel was not found",
+	}
+
+	seen := map[string]ritaError{}
+
+	for e, message := range messages {
+		if e.Error() != message {
+			t.Errorf("%d: expected %q, got %q", e, message, e.Error())
+		}
+
+		if other, ok := seen[message]; ok {
+			t.Errorf("%d and %d share the message %q", e, other, message)
+		}
+		seen[message] = e
+	}
+}
+
+func TestRitaErrorSentinels(t *testing.T) {
+	err := fmt.Errorf("get cursor: %w", NotAuthorized)
+
+	if !errors.Is(err, ErrNotAuthorized) {
+		t.Errorf("expected wrapped NotAuthorized to match ErrNotAuthorized")
+	}
+
+	if errors.Is(err, ErrForbidden) {
+		t.Errorf("NotAuthorized must not match ErrForbidden")
+	}
+}
+
+func TestRitaEventCreatedAt(t *testing.T) {
+	expected := time.Date(2025, 1, 6, 18, 16, 0, 563000000, time.UTC)
+
+	cases := map[string]time.Time{
+		`{"id":"1-0","createdAt":"2025-01-06T18:16:00.563Z"}`:      expected,
+		`{"id":"1-0","createdAt":"2025-01-06T19:16:00.563+01:00"}`: expected,
+		`{"id":"1-0","createdAt":1736187360563}`:                   expected,
+		`{"id":"1-0","createdAt":"1736187360563"}`:                 expected,
+		`{"id":"1-0","createdAt":1736187360.563}`:                  expected,
+		`{"id":"1-0","createdAt":1736187360}`:                      expected.Truncate(time.Second),
+		`{"id":"1-0","createdAt":""}`:                              {},
+		`{"id":"1-0","createdAt":null}`:                            {},
+		`{"id":"1-0"}`:                                             {},
+	}
+
+	for payload, createdAt := range cases {
+		var event RitaEvent
+		if err := json.Unmarshal([]byte(payload), &event); err != nil {
+			t.Errorf("%s: %v", payload, err)
+			continue
+		}
+
+		if event.Id != "1-0" {
+			t.Errorf("%s: expected id 1-0, got %q", payload, event.Id)
+		}
+
+		if !event.CreatedAt.Equal(createdAt) || event.CreatedAt.Location() != time.UTC {
+			t.Errorf("%s: expected %v, got %v", payload, createdAt, event.CreatedAt)
+		}
+	}
+}
+
+func TestRitaEventCreatedAtInvalid(t *testing.T) {
+	var event RitaEvent
+	if err := json.Unmarshal([]byte(`{"id":"1-0","createdAt":"yesterday"}`), &event); err == nil {
+		t.Errorf("expected an error for an unknown createdAt layout")
+	}
+}
+
+func TestNotFoundMatchesForbidden(t *testing.T) {
+	if !errors.Is(NotFound, Forbidden) {
+		t.Errorf("expected NotFound to match Forbidden")
+	}
+
+	if errors.Is(Forbidden, NotFound) {
+		t.Errorf("Forbidden must not match NotFound")
+	}
+
+	if NotFound == Forbidden {
+		t.Errorf("NotFound and Forbidden must be distinct")
+	}
+}
+
+func TestRitaEventDecode(t *testing.T) {
+	type order struct {
+		Id     string `json:"id"`
+		Amount int64  `json:"amount"`
+	}
+
+	var event RitaEvent
+	if err := json.Unmarshal([]byte(`{"id":"1-0","data":{"id":"a","amount":12345678901234567}}`), &event); err != nil {
+		t.Fatal(err)
+	}
+
+	var decoded order
+	if err := event.Decode(&decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	// decoding the received JSON keeps the precision lost by the float64 of Data
+	if decoded != (order{Id: "a", Amount: 12345678901234567}) {
+		t.Errorf("unexpected order %+v", decoded)
+	}
+
+	built := RitaEvent{Id: "2-0", Data: map[string]interface{}{"id": "b", "amount": 3}}
+	if err := built.Decode(&decoded); err != nil || decoded != (order{Id: "b", Amount: 3}) {
+		t.Errorf("expected Data to be marshaled, got %+v %v", decoded, err)
+	}
+
+	var amount int
+	err := event.Decode(&amount)
+	if !errors.Is(err, DataNotDecodable) {
+		t.Errorf("expected DataNotDecodable, got %v", err)
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		t.Errorf("expected the error of encoding/json to be wrapped, got %v", err)
+	}
+
+	// events stay comparable, as long as their Data is
+	var a, b RitaEvent
+	json.Unmarshal([]byte(`{"id":"1-0","data":"x"}`), &a)
+	json.Unmarshal([]byte(`{"id":"1-0","data":"x"}`), &b)
+	if a != b || len(map[RitaEvent]bool{a: true, b: true}) != 1 {
+		t.Errorf("expected equal events to compare equal")
+	}
+}
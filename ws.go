@@ -0,0 +1,130 @@
+package ritago
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/Pyxis-GMS/rita-go/internal/websocket"
+)
+
+/*
+SubEventWS returns a channel that will receive events from the specified channel, like SubEvent,
+over a WebSocket connection instead of server-sent events.
+
+Intermediaries that buffer or cut SSE streams usually let WebSocket connections through. The
+connection is opened on the /v1/event/{channel}/ws endpoint with the same auth header, proxy and
+reconnection behavior as SubEvent, and every text message is an event encoded as JSON.
+
+Parameters:
+  - channel: The name of the channel from which to receive events.
+
+Returns:
+  - chan *RitaEvent: A channel that will receive events from the specified channel.
+  - error: An error if the request fails or the channel cannot be accessed.
+
+# Example
+
+	...
+	events, _ := client.SubEventWS("test")
+	for event := range events {
+		fmt.Println(event)
+	}
+	...
+*/
+func (c *RitaClient) SubEventWS(channel string) (chan *RitaEvent, error) {
+	sub, err := c.SubscribeWS(context.Background(), channel, "")
+	if err != nil {
+		return nil, err
+	}
+
+	return sub.events, nil
+}
+
+// SubscribeWS opens a subscription like Subscribe over a WebSocket connection.
+func (c *RitaClient) SubscribeWS(ctx context.Context, channel string, eventId string, opts ...SubscribeOption) (*Subscription, error) {
+	return c.subscribe(ctx, channel, eventId, c.openWebSocket, opts)
+}
+
+// openWebSocket opens the WebSocket connection of a subscription.
+func (c *RitaClient) openWebSocket(ctx context.Context, channel string, eventId string) (stream, error) {
+	queryParams := map[string]string{
+		"eventId": "",
+	}
+
+	if strings.TrimSpace(eventId) != "" {
+		queryParams["eventId"] = eventId
+	}
+
+	url, err := c.createUrl(channel, c.urlEventWS, &queryParams)
+	if err != nil {
+		return nil, err
+	}
+
+	key, err := websocket.NewKey()
+	if err != nil {
+		return nil, err
+	}
+
+	c.logger.Debug("rita request", "method", "GET", "url", url)
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	c.setAuth(req)
+	websocket.SetHandshakeHeaders(req.Header, key)
+
+	c.decorateRequest(ctx, req)
+
+	start := time.Now()
+	resp, err := c.sseClient.Do(req)
+	if err != nil {
+		c.observe("GET", channel, 0, start)
+		return nil, err
+	}
+	c.observe("GET", channel, resp.StatusCode, start)
+
+	switch resp.StatusCode {
+	case http.StatusSwitchingProtocols:
+		conn, err := websocket.Upgrade(resp, key)
+		if err != nil {
+			resp.Body.Close()
+			return nil, err
+		}
+
+		conn.MaxMessageSize = int64(c.maxFrameSize)
+
+		return &wsStream{conn: conn}, nil
+	case 200:
+		resp.Body.Close()
+		return nil, websocket.ErrBadHandshake
+	default:
+		resp.Body.Close()
+		return nil, statusError(resp.StatusCode)
+	}
+}
+
+// wsStream is a stream reading the messages of a WebSocket connection, each
+// of them being the data of a frame.
+type wsStream struct {
+	conn *websocket.Conn
+}
+
+func (s *wsStream) next() (sseFrame, error) {
+	message, err := s.conn.ReadMessage()
+	if err == websocket.ErrMessageTooLarge {
+		return sseFrame{}, FrameTooLarge
+	}
+	if err != nil {
+		return sseFrame{}, err
+	}
+
+	return sseFrame{data: strings.TrimSpace(string(message))}, nil
+}
+
+func (s *wsStream) Close() error {
+	return s.conn.Close()
+}
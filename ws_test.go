@@ -0,0 +1,128 @@
+package ritago
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Pyxis-GMS/rita-go/internal/websocket"
+)
+
+// newWebSocketServer upgrades the requests on the ws endpoint and sends each
+// message of messages as a text frame, then closes the connection.
+func newWebSocketServer(t *testing.T, messages func(eventId string) []string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/event/test/ws" || r.Header.Get("Upgrade") != "websocket" {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+
+		if r.Header.Get("Authorization") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		conn, rw, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			t.Error(err)
+			return
+		}
+		defer conn.Close()
+
+		fmt.Fprintf(rw, "HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: %s\r\n\r\n",
+			websocket.AcceptKey(r.Header.Get("Sec-WebSocket-Key")))
+
+		for _, message := range messages(r.URL.Query().Get("eventId")) {
+			frame := []byte{0x81}
+			if len(message) < 126 {
+				frame = append(frame, byte(len(message)))
+			} else {
+				frame = append(frame, 126)
+				frame = binary.BigEndian.AppendUint16(frame, uint16(len(message)))
+			}
+			rw.Write(append(frame, message...))
+		}
+		rw.Write([]byte{0x88, 0x02, 0x03, 0xe8})
+		rw.Flush()
+
+		// wait for the close of the client
+		conn.SetReadDeadline(time.Now().Add(time.Second))
+		rw.Read(make([]byte, 64))
+	}))
+	t.Cleanup(server.Close)
+
+	return server
+}
+
+func TestSubEventWS(t *testing.T) {
+	server := newWebSocketServer(t, func(string) []string {
+		return []string{`{"id":"1-0","data":{"key":"value"}}`, "ping", `{"id":"2-0"}`}
+	})
+
+	events, err := New(server.URL, "key").SubEventWS("test")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var ids []string
+	for event := range events {
+		ids = append(ids, event.Id)
+	}
+
+	if fmt.Sprint(ids) != "[1-0 2-0]" {
+		t.Errorf("unexpected events %v", ids)
+	}
+
+	if _, err := New(server.URL, "other").SubEventWS("test"); err != NotAuthorized {
+		t.Errorf("expected NotAuthorized, got %v", err)
+	}
+}
+
+func TestSubscribeWSReconnects(t *testing.T) {
+	var mu sync.Mutex
+	var since []string
+
+	server := newWebSocketServer(t, func(eventId string) []string {
+		mu.Lock()
+		defer mu.Unlock()
+
+		since = append(since, eventId)
+		if len(since) > 2 {
+			return nil
+		}
+		return []string{fmt.Sprintf(`{"id":"%d-0"}`, len(since)), `{"error":"going away"}`}
+	})
+
+	client := New(server.URL, "key", WithReconnect(time.Millisecond, 5*time.Millisecond))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sub, err := client.SubscribeWS(ctx, "test", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, expected := range []string{"1-0", "2-0"} {
+		if event := <-sub.Events(); event.Id != expected {
+			t.Fatalf("expected %s, got %s", expected, event.Id)
+		}
+	}
+
+	if err := <-sub.Errors(); err == nil || err.Error() != "server error: going away" {
+		t.Errorf("expected the error message to be reported, got %v", err)
+	}
+
+	sub.Close()
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(since) < 2 || since[1] != "1-0" {
+		t.Errorf("expected to reconnect from 1-0, got %v", since)
+	}
+}